@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultRetryBackoff is the delay before the first retry of Open when
+// Config.RetryBackoff is not set.
+const defaultRetryBackoff = time.Second
+
+// Config holds the settings used by Open to create the connection pool.
+//
+// Fields:
+//   - Host, Port, User, Password, Database: Connection target and credentials.
+//   - SSLMode: The libpq sslmode (disable, require, verify-ca, verify-full...). Defaults to "disable".
+//   - SSLRootCert: Path to the CA certificate used by the verify-* modes.
+//   - ApplicationName: Reported to Postgres as application_name, visible in pg_stat_activity.
+//   - MaxConns, MinConns: Pool size bounds. Zero keeps the pgxpool defaults.
+//   - ConnectTimeout: Timeout for establishing each connection.
+//   - MaxConnIdleTime, MaxConnLifetime: When idle or old connections are closed. Zero keeps the pgxpool defaults.
+//   - ConnectRetries: How many times Open retries after a failed attempt.
+//   - RetryBackoff: Delay before the first retry, doubled after each attempt up to RetryMaxBackoff. Defaults to 1s.
+//   - Replicas: Read replica addresses (host or host:port, default port Port) sharing the other settings.
+//   - ReplicaCheckInterval: How often replicas are pinged to decide whether they receive reads.
+//   - SlowQueryThreshold: Queries taking longer are logged (see Tracer). Zero keeps the default, negative disables it.
+type Config struct {
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	Database        string
+	SSLMode         string
+	SSLRootCert     string
+	ApplicationName string
+	MaxConns        int32
+	MinConns        int32
+	ConnectTimeout  time.Duration
+	MaxConnIdleTime time.Duration
+	MaxConnLifetime time.Duration
+	ConnectRetries  int
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+
+	Replicas             []string
+	ReplicaCheckInterval time.Duration
+	SlowQueryThreshold   time.Duration
+}
+
+// ConfigFromEnv reads the Config from environment variables. The connection
+// target keeps the variables used so far (POSTGRES_USER, POSTGRES_PASSWORD,
+// DATABASE_HOST, DATABASE_PORT, POSTGRES_DB) and the rest are optional:
+//
+//   - DATABASE_SSLMODE, DATABASE_SSLROOTCERT, DATABASE_APPLICATION_NAME
+//   - DATABASE_MAX_CONNS, DATABASE_MIN_CONNS, DATABASE_CONNECT_RETRIES (integers)
+//   - DATABASE_CONNECT_TIMEOUT, DATABASE_MAX_CONN_IDLE_TIME, DATABASE_MAX_CONN_LIFETIME (durations such as "5s")
+//   - DATABASE_REPLICAS (comma separated host[:port] list), DATABASE_REPLICA_CHECK_INTERVAL (duration)
+//   - DATABASE_SLOW_QUERY_THRESHOLD (duration, "-1s" disables the slow query log)
+//
+// It returns an error if a numeric or duration variable cannot be parsed.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Host:            os.Getenv("DATABASE_HOST"),
+		Port:            os.Getenv("DATABASE_PORT"),
+		User:            os.Getenv("POSTGRES_USER"),
+		Password:        os.Getenv("POSTGRES_PASSWORD"),
+		Database:        os.Getenv("POSTGRES_DB"),
+		SSLMode:         os.Getenv("DATABASE_SSLMODE"),
+		SSLRootCert:     os.Getenv("DATABASE_SSLROOTCERT"),
+		ApplicationName: os.Getenv("DATABASE_APPLICATION_NAME"),
+		ConnectTimeout:  5 * time.Second,
+		ConnectRetries:  5,
+		RetryBackoff:    defaultRetryBackoff,
+		RetryMaxBackoff: 30 * time.Second,
+
+		ReplicaCheckInterval: 10 * time.Second,
+		SlowQueryThreshold:   DefaultSlowQueryThreshold,
+	}
+	for _, host := range strings.Split(os.Getenv("DATABASE_REPLICAS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			cfg.Replicas = append(cfg.Replicas, host)
+		}
+	}
+
+	var err error
+	if cfg.MaxConns, err = envInt32("DATABASE_MAX_CONNS", cfg.MaxConns); err != nil {
+		return Config{}, err
+	}
+	if cfg.MinConns, err = envInt32("DATABASE_MIN_CONNS", cfg.MinConns); err != nil {
+		return Config{}, err
+	}
+	retries, err := envInt32("DATABASE_CONNECT_RETRIES", int32(cfg.ConnectRetries))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.ConnectRetries = int(retries)
+	if cfg.ConnectTimeout, err = envDuration("DATABASE_CONNECT_TIMEOUT", cfg.ConnectTimeout); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxConnIdleTime, err = envDuration("DATABASE_MAX_CONN_IDLE_TIME", cfg.MaxConnIdleTime); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxConnLifetime, err = envDuration("DATABASE_MAX_CONN_LIFETIME", cfg.MaxConnLifetime); err != nil {
+		return Config{}, err
+	}
+	if cfg.ReplicaCheckInterval, err = envDuration("DATABASE_REPLICA_CHECK_INTERVAL", cfg.ReplicaCheckInterval); err != nil {
+		return Config{}, err
+	}
+	if cfg.SlowQueryThreshold, err = envDuration("DATABASE_SLOW_QUERY_THRESHOLD", cfg.SlowQueryThreshold); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func envInt32(key string, fallback int32) (int32, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return int32(n), nil
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return d, nil
+}
+
+// replica returns c pointed at the replica address host[:port].
+func (c Config) replica(address string) Config {
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		c.Host, c.Port = host, port
+	} else {
+		c.Host = address
+	}
+	c.Replicas = nil
+	return c
+}
+
+func (c Config) url() *url.URL {
+	query := url.Values{}
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	query.Set("sslmode", sslMode)
+	if c.SSLRootCert != "" {
+		query.Set("sslrootcert", c.SSLRootCert)
+	}
+	return &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, c.Port),
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+}
+
+// DSN returns the connection string, including the password.
+// Use String to log it.
+func (c Config) DSN() string {
+	return c.url().String()
+}
+
+// String returns the connection string with the password redacted.
+func (c Config) String() string {
+	return c.url().Redacted()
+}
+
+// PoolConfig converts c into a pgxpool.Config, with Queries installed as the
+// connection tracer.
+func (c Config) PoolConfig() (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(c.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid database config %s: %w", c, err)
+	}
+	if c.MaxConns > 0 {
+		poolConfig.MaxConns = c.MaxConns
+	}
+	if c.MinConns > 0 {
+		poolConfig.MinConns = c.MinConns
+	}
+	if c.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	if c.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = c.ConnectTimeout
+	}
+	if c.ApplicationName != "" {
+		poolConfig.ConnConfig.RuntimeParams["application_name"] = c.ApplicationName
+	}
+	poolConfig.ConnConfig.Tracer = Queries
+	return poolConfig, nil
+}
+
+// Open creates a connection pool for cfg and pings the database, retrying
+// with exponential backoff up to cfg.ConnectRetries times. It returns the last
+// error, with the password redacted, if the database never becomes reachable
+// or ctx is done first.
+func Open(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
+	poolConfig, err := cfg.PoolConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SlowQueryThreshold != 0 {
+		Queries.SetSlowThreshold(cfg.SlowQueryThreshold)
+	}
+
+	backoff := cfg.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err == nil {
+			if err = pool.Ping(ctx); err == nil {
+				return pool, nil
+			}
+			pool.Close()
+		}
+		if attempt >= cfg.ConnectRetries {
+			return nil, fmt.Errorf("error connecting to %s after %d attempts: %w", cfg, attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error connecting to %s: %w", cfg, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if cfg.RetryMaxBackoff > 0 && backoff > cfg.RetryMaxBackoff {
+			backoff = cfg.RetryMaxBackoff
+		}
+	}
+}
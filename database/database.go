@@ -2,9 +2,7 @@ package database
 
 import (
 	"context"
-	"fmt"
 	"os"
-	"time"
 
 	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,61 +13,66 @@ import (
 // The pool is created when the Connect function is called.
 var DB *pgxpool.Pool
 
-// Connect establishes a connection to the PostgreSQL database using environment variables.
+// Connect establishes a connection to the PostgreSQL database using environment variables
+// (see ConfigFromEnv) and stores the pool in DB. Configured read replicas are
+// registered as well (see ConnectReplicas).
 // It also executes any necessary migrations to ensure required tables exist in the database.
-func Connect() {
-	// Retrieve database connection info from environment variables
-	databaseInfo := fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("DATABASE_HOST"),
-		os.Getenv("DATABASE_PORT"),
-		os.Getenv("POSTGRES_DB"),
-	)
-
-	logger.Logger.Debugf("Attempting to connect to database with connection string: %s", databaseInfo)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	var err error
-	logger.Logger.Debug("Creating database connection pool")
-	DB, err = pgxpool.New(ctx, databaseInfo)
-
+// It returns an error if the configuration is invalid, the database is not reachable
+// or the versioned migrations fail (see RunMigrations).
+func Connect() error {
+	cfg, err := ConfigFromEnv()
 	if err != nil {
-		logger.Logger.Errorf("Failed to create database connection pool: %v", err)
+		logger.Logger.Errorf("Invalid database configuration: %v", err)
+		return err
 	}
 
-	// Ping the database to ensure it's reachable
-	err = DB.Ping(ctx)
-	logger.Logger.Debug("Pinging the database to check connectivity")
+	logger.Logger.Debugf("Attempting to connect to database with connection string: %s", cfg)
+
+	pool, err := Open(context.Background(), cfg)
 	if err != nil {
 		logger.Logger.Errorf("Database is not reachable: %v", err)
+		return err
 	}
+	DB = pool
+	if err := ConnectReplicas(context.Background(), cfg); err != nil {
+		logger.Logger.Errorf("Invalid database replica configuration: %v", err)
+		return err
+	}
+	RegisterHealthCheck()
 
 	logger.Logger.Info("Database connection established successfully")
 
 	// Run necessary migrations
-	RunMigrations()
+	return RunMigrations()
 }
 
-// RunMigrations executes the database migration process by reading SQL commands
-// from a migration file and applying them to the database. It logs the progress
+// RunMigrations executes the database migration process and logs the progress
 // and any errors encountered during the process.
 //
-// The function performs the following steps:
-// 1. Reads the migration SQL file located at "./src/database/migrations.sql".
+// When the MIGRATION_DIR environment variable is set, the versioned migrations
+// in that directory are applied with Migrate. Otherwise the legacy behavior is
+// kept:
+// 1. Reads the migration SQL file located at MIGRATION_FILE (default "./src/database/migrations.sql").
 // 2. Executes the SQL commands in the migration file against the database.
 // 3. Logs debug, error, and informational messages to indicate the status of the migration.
 //
-// If an error occurs while reading the migration file or executing the SQL commands,
-// the error is logged, and the function continues execution.
-func RunMigrations() {
+// An error applying the versioned migrations is logged and returned. In the
+// legacy mode, an error reading the migration file or executing the SQL commands
+// is only logged, and the function continues execution.
+func RunMigrations() error {
 	logger.Logger.Debug("Running database migrations")
 
 	ctx := context.Background()
 
+	if migrationDir := os.Getenv("MIGRATION_DIR"); migrationDir != "" {
+		if err := Migrate(ctx, os.DirFS(migrationDir)); err != nil {
+			logger.Logger.Errorf("Error executing migrations: %v", err)
+			return err
+		}
+		logger.Logger.Info("Database migrations completed successfully")
+		return nil
+	}
+
 	migrationFilePath := os.Getenv("MIGRATION_FILE")
 	if migrationFilePath == "" {
 		logger.Logger.Debug("MIGRATION_FILE environment variable not set, using default path")
@@ -85,8 +88,9 @@ func RunMigrations() {
 	_, err = DB.Exec(ctx, string(migrationSQL))
 	if err != nil {
 		logger.Logger.Errorf("Error executing migrations: %v", err)
-		return
+		return nil
 	} else {
 		logger.Logger.Info("Database migrations completed successfully")
 	}
+	return nil
 }
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/health"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// HealthCheckName is the name the database registers under in the health registry.
+const HealthCheckName = "database"
+
+// HealthCheck pings DB and reports the ping latency, the pool statistics, the
+// state of the read replicas and the latest applied migration version. An
+// unhealthy replica does not make the check fail, since reads fall back to
+// the primary.
+func HealthCheck(ctx context.Context) health.Check {
+	if DB == nil {
+		return health.Check{Status: health.StatusDown, Error: "database not connected"}
+	}
+
+	start := time.Now()
+	if err := DB.Ping(ctx); err != nil {
+		return health.Check{Status: health.StatusDown, Latency: time.Since(start).String(), Error: err.Error()}
+	}
+	latency := time.Since(start)
+
+	stat := DB.Stat()
+	details := map[string]any{
+		"acquired_conns":     stat.AcquiredConns(),
+		"idle_conns":         stat.IdleConns(),
+		"constructing_conns": stat.ConstructingConns(),
+		"total_conns":        stat.TotalConns(),
+		"max_conns":          stat.MaxConns(),
+		"waited_acquires":    stat.EmptyAcquireCount(),
+		"waited_duration":    stat.EmptyAcquireWaitTime().String(),
+		"canceled_acquires":  stat.CanceledAcquireCount(),
+	}
+
+	if replicaStatuses := Replicas(); len(replicaStatuses) > 0 {
+		details["replicas"] = replicaStatuses
+	}
+
+	version, err := migrationVersion(ctx)
+	if err != nil {
+		details["migration_error"] = err.Error()
+	} else {
+		details["migration_version"] = version
+	}
+
+	return health.Check{Status: health.StatusUp, Latency: latency.String(), Details: details}
+}
+
+// RegisterHealthCheck adds HealthCheck to the health registry.
+func RegisterHealthCheck() {
+	health.Register(HealthCheckName, health.CheckerFunc(HealthCheck))
+}
+
+// migrationVersion returns the highest version in schema_migrations, or 0 if
+// no versioned migration was ever applied.
+func migrationVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := DB.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42P01" {
+		return 0, nil
+	}
+	return version, err
+}
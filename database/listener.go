@@ -0,0 +1,247 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
+	"github.com/jackc/pgx/v5"
+)
+
+// Notification is a message received on a LISTEN channel.
+type Notification struct {
+	Channel string
+	Payload string
+	PID     uint32
+}
+
+// Listener holds a dedicated connection LISTENing on a set of channels and
+// dispatches every notification to the handlers registered for its channel.
+// When the connection is lost it reconnects with exponential backoff and
+// LISTENs again on every channel, so handlers keep receiving notifications
+// (those sent while disconnected are lost, as Postgres does not queue them).
+//
+// Example:
+//
+//	listener := database.NewListener()
+//	database.OnJSON(listener, "grades_changed", func(g Grade) {
+//	    notifications.Send(g.StudentID, ...)
+//	})
+//	go listener.Run(ctx)
+type Listener struct {
+	config     *pgx.ConnConfig
+	maxBackoff time.Duration
+
+	mu       sync.Mutex
+	handlers map[string][]func(Notification)
+	wake     context.CancelFunc
+}
+
+// NewListener creates a Listener connecting with the settings of DB.
+func NewListener() *Listener {
+	return NewListenerWithConfig(DB.Config().ConnConfig.Copy())
+}
+
+// NewListenerWithConfig creates a Listener connecting with config.
+func NewListenerWithConfig(config *pgx.ConnConfig) *Listener {
+	return &Listener{
+		config:     config,
+		maxBackoff: 30 * time.Second,
+		handlers:   map[string][]func(Notification){},
+	}
+}
+
+// Listen registers handler for the notifications sent to channel. Handlers run
+// sequentially on the Run goroutine, so slow work should be handed off.
+func (l *Listener) Listen(channel string, handler func(Notification)) {
+	l.mu.Lock()
+	l.handlers[channel] = append(l.handlers[channel], handler)
+	wake := l.wake
+	l.mu.Unlock()
+	if wake != nil {
+		wake()
+	}
+}
+
+// Unlisten removes every handler of channel and stops LISTENing on it.
+func (l *Listener) Unlisten(channel string) {
+	l.mu.Lock()
+	delete(l.handlers, channel)
+	wake := l.wake
+	l.mu.Unlock()
+	if wake != nil {
+		wake()
+	}
+}
+
+// Subscribe returns a Go channel receiving the notifications sent to channel.
+// Notifications are dropped, with a warning, when the buffer is full.
+func (l *Listener) Subscribe(channel string, buffer int) <-chan Notification {
+	ch := make(chan Notification, buffer)
+	l.Listen(channel, func(n Notification) {
+		select {
+		case ch <- n:
+		default:
+			logger.Logger.Warnf("Dropping notification on %s: subscriber is not keeping up", n.Channel)
+		}
+	})
+	return ch
+}
+
+// OnJSON registers fn for the notifications sent to channel, decoding their
+// payload as JSON into T. Payloads that cannot be decoded are logged and
+// skipped.
+func OnJSON[T any](l *Listener, channel string, fn func(T)) {
+	l.Listen(channel, func(n Notification) {
+		var payload T
+		if err := json.Unmarshal([]byte(n.Payload), &payload); err != nil {
+			logger.Logger.Errorf("Invalid payload on %s: %v", n.Channel, err)
+			return
+		}
+		fn(payload)
+	})
+}
+
+// SubscribeJSON is Subscribe decoding payloads as JSON into T, like OnJSON.
+func SubscribeJSON[T any](l *Listener, channel string, buffer int) <-chan T {
+	ch := make(chan T, buffer)
+	OnJSON(l, channel, func(payload T) {
+		select {
+		case ch <- payload:
+		default:
+			logger.Logger.Warnf("Dropping notification on %s: subscriber is not keeping up", channel)
+		}
+	})
+	return ch
+}
+
+// Notify sends payload to channel with pg_notify. A string or []byte payload
+// is sent as is and anything else is encoded as JSON.
+func Notify(ctx context.Context, channel string, payload any) error {
+	var text string
+	switch p := payload.(type) {
+	case string:
+		text = p
+	case []byte:
+		text = string(p)
+	default:
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("error encoding notification: %v", err)
+		}
+		text = string(b)
+	}
+	if _, err := DB.Exec(ctx, "SELECT pg_notify($1, $2)", channel, text); err != nil {
+		return fmt.Errorf("error sending notification on %s: %w", channel, err)
+	}
+	return nil
+}
+
+// Run connects and dispatches notifications until ctx is done, reconnecting
+// whenever the connection is lost. It returns ctx.Err().
+func (l *Listener) Run(ctx context.Context) error {
+	backoff := time.Second
+	for {
+		connected, err := l.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			backoff = time.Second
+		}
+		logger.Logger.Warnf("Listener connection lost, reconnecting in %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > l.maxBackoff {
+			backoff = l.maxBackoff
+		}
+	}
+}
+
+// session runs one connection until it fails. connected reports whether the
+// connection was established at all.
+func (l *Listener) session(ctx context.Context) (connected bool, err error) {
+	conn, err := pgx.ConnectConfig(ctx, l.config)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	listening := map[string]bool{}
+	for {
+		// wake is published before syncing, so a Listen racing with sync
+		// still interrupts the wait below.
+		waitCtx, wake := context.WithCancel(ctx)
+		l.mu.Lock()
+		l.wake = wake
+		l.mu.Unlock()
+
+		if err := l.sync(ctx, conn, listening); err != nil {
+			wake()
+			return true, err
+		}
+		n, err := conn.WaitForNotification(waitCtx)
+		woken := waitCtx.Err() != nil && ctx.Err() == nil
+
+		l.mu.Lock()
+		l.wake = nil
+		l.mu.Unlock()
+		wake()
+
+		if n != nil {
+			l.dispatch(Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID})
+		}
+		if err != nil && (!woken || conn.IsClosed()) {
+			return true, err
+		}
+		// Woken up by Listen or Unlisten: sync the channels and wait again.
+	}
+}
+
+// sync issues LISTEN and UNLISTEN so the connection listens on exactly the
+// channels with handlers. listening tracks the connection's current state.
+func (l *Listener) sync(ctx context.Context, conn *pgx.Conn, listening map[string]bool) error {
+	l.mu.Lock()
+	wanted := make(map[string]bool, len(l.handlers))
+	for channel := range l.handlers {
+		wanted[channel] = true
+	}
+	l.mu.Unlock()
+
+	for channel := range wanted {
+		if listening[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("error listening on %s: %w", channel, err)
+		}
+		listening[channel] = true
+	}
+	for channel := range listening {
+		if wanted[channel] {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("error unlistening %s: %w", channel, err)
+		}
+		delete(listening, channel)
+	}
+	return nil
+}
+
+func (l *Listener) dispatch(n Notification) {
+	l.mu.Lock()
+	handlers := append([]func(Notification){}, l.handlers[n.Channel]...)
+	l.mu.Unlock()
+	for _, handler := range handlers {
+		handler(n)
+	}
+}
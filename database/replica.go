@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ForcePrimaryKey is the gin context key that, set to true, makes Reader
+// return the primary for the rest of the request (read-your-writes).
+const ForcePrimaryKey = "db_force_primary"
+
+type forcePrimaryKey struct{}
+
+// ReplicaStatus describes a read replica, as reported by the health check.
+type ReplicaStatus struct {
+	Host    string `json:"host"`
+	Healthy bool   `json:"healthy"`
+}
+
+type replica struct {
+	host    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// replicas holds the read replica pools. A replica only receives reads while
+// healthy; CheckReplicas updates that state.
+var replicas struct {
+	sync.RWMutex
+	list []*replica
+	next atomic.Uint64
+	stop context.CancelFunc
+}
+
+// AddReplica registers pool as a read replica. It starts as healthy; use
+// CheckReplicas or MonitorReplicas to keep that state up to date.
+func AddReplica(host string, pool *pgxpool.Pool) {
+	r := &replica{host: host, pool: pool}
+	r.healthy.Store(true)
+	replicas.Lock()
+	replicas.list = append(replicas.list, r)
+	replicas.Unlock()
+}
+
+// ConnectReplicas creates a pool for each host in cfg.Replicas, using the rest
+// of cfg (credentials, database, pool settings), and starts monitoring them
+// every cfg.ReplicaCheckInterval until CloseReplicas is called.
+//
+// Replicas that are not reachable yet do not make it fail: they stay
+// unhealthy, reads go to the primary, and they join once they answer.
+func ConnectReplicas(ctx context.Context, cfg Config) error {
+	for _, host := range cfg.Replicas {
+		replicaCfg := cfg.replica(host)
+		poolConfig, err := replicaCfg.PoolConfig()
+		if err != nil {
+			return err
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			return fmt.Errorf("error creating replica pool %s: %w", replicaCfg, err)
+		}
+		AddReplica(net.JoinHostPort(replicaCfg.Host, replicaCfg.Port), pool)
+	}
+	if len(cfg.Replicas) == 0 {
+		return nil
+	}
+
+	CheckReplicas(ctx)
+	monitorCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	replicas.Lock()
+	replicas.stop = stop
+	replicas.Unlock()
+	go MonitorReplicas(monitorCtx, cfg.ReplicaCheckInterval)
+	return nil
+}
+
+// CheckReplicas pings every replica and updates whether it receives reads.
+func CheckReplicas(ctx context.Context) {
+	replicas.RLock()
+	list := replicas.list
+	replicas.RUnlock()
+
+	for _, r := range list {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := r.pool.Ping(pingCtx)
+		cancel()
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				logger.Logger.Infof("Database replica %s is healthy again", r.host)
+			} else {
+				logger.Logger.Warnf("Database replica %s is unhealthy, reading from primary: %v", r.host, err)
+			}
+		}
+	}
+}
+
+// MonitorReplicas runs CheckReplicas every interval until ctx is done.
+func MonitorReplicas(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			CheckReplicas(ctx)
+		}
+	}
+}
+
+// CloseReplicas stops monitoring and closes every replica pool.
+func CloseReplicas() {
+	replicas.Lock()
+	list, stop := replicas.list, replicas.stop
+	replicas.list, replicas.stop = nil, nil
+	replicas.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+	for _, r := range list {
+		r.pool.Close()
+	}
+}
+
+// Replicas reports the state of every read replica.
+func Replicas() []ReplicaStatus {
+	replicas.RLock()
+	defer replicas.RUnlock()
+	statuses := make([]ReplicaStatus, len(replicas.list))
+	for i, r := range replicas.list {
+		statuses[i] = ReplicaStatus{Host: r.host, Healthy: r.healthy.Load()}
+	}
+	return statuses
+}
+
+// ForcePrimary returns a copy of ctx whose reads go to the primary, e.g. to
+// read back a row right after writing it. Inside gin handlers
+// c.Set(database.ForcePrimaryKey, true) has the same effect.
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// PrimaryForced reports whether ctx was marked by ForcePrimary or carries
+// ForcePrimaryKey.
+func PrimaryForced(ctx context.Context) bool {
+	if forced, ok := ctx.Value(forcePrimaryKey{}).(bool); ok && forced {
+		return true
+	}
+	forced, _ := ctx.Value(ForcePrimaryKey).(bool)
+	return forced
+}
+
+// Reader returns the pool read queries should use: a healthy replica, chosen
+// round robin, or DB when there is none or ctx forces the primary.
+func Reader(ctx context.Context) *pgxpool.Pool {
+	if PrimaryForced(ctx) {
+		return DB
+	}
+	replicas.RLock()
+	defer replicas.RUnlock()
+	n := len(replicas.list)
+	if n == 0 {
+		return DB
+	}
+	start := replicas.next.Add(1)
+	for i := 0; i < n; i++ {
+		r := replicas.list[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r.pool
+		}
+	}
+	return DB
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxFingerprints bounds the number of distinct queries tracked by a Tracer.
+// Queries seen after the limit is reached are aggregated under otherQueries.
+const (
+	maxFingerprints = 1000
+	otherQueries    = "other"
+)
+
+// DefaultSlowQueryThreshold is the slow query threshold used when
+// DATABASE_SLOW_QUERY_THRESHOLD is not set.
+const DefaultSlowQueryThreshold = 500 * time.Millisecond
+
+// Queries is the Tracer installed on the pools created by Open. Its Stats
+// cover every query run by the service.
+var Queries = NewTracer()
+
+var (
+	quotedLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	placeholders   = regexp.MustCompile(`\(\s*(?:\$n|\?)(?:\s*,\s*(?:\$n|\?))+\s*\)`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// QueryStats aggregates the executions of the queries sharing a fingerprint.
+type QueryStats struct {
+	Fingerprint   string        `json:"fingerprint"`
+	Calls         int64         `json:"calls"`
+	Errors        int64         `json:"errors"`
+	RowsAffected  int64         `json:"rows_affected"`
+	TotalDuration time.Duration `json:"total_duration"`
+	MaxDuration   time.Duration `json:"max_duration"`
+}
+
+// MeanDuration returns the average duration of a call.
+func (s QueryStats) MeanDuration() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Calls)
+}
+
+// Tracer is a pgx QueryTracer, BatchTracer and CopyFromTracer recording the
+// duration and rows affected of every query, aggregated by fingerprint, and
+// logging the queries slower than its threshold. Arguments are never logged,
+// only their types.
+type Tracer struct {
+	slowThreshold atomic.Int64
+
+	mu    sync.Mutex
+	stats map[string]*QueryStats
+}
+
+type traceKey struct{}
+
+// trace is the state kept in the context between the start and end callbacks.
+type trace struct {
+	sql   string
+	args  []any
+	start time.Time
+	// last is when the previous query of a batch finished.
+	last time.Time
+}
+
+// NewTracer creates a Tracer logging queries slower than DefaultSlowQueryThreshold.
+func NewTracer() *Tracer {
+	t := &Tracer{stats: map[string]*QueryStats{}}
+	t.SetSlowThreshold(DefaultSlowQueryThreshold)
+	return t
+}
+
+// SetSlowThreshold sets the duration above which queries are logged. Zero or
+// a negative value disables the slow query log.
+func (t *Tracer) SetSlowThreshold(threshold time.Duration) {
+	t.slowThreshold.Store(int64(threshold))
+}
+
+// Fingerprint normalizes sql so that queries differing only in literals,
+// placeholder lists or whitespace share the same statistics:
+//
+//	SELECT * FROM users WHERE id IN ($1, $2) AND name = 'x' AND age > $3
+//	-> SELECT * FROM users WHERE id IN (...) AND name = ? AND age > $n
+func Fingerprint(sql string) string {
+	sql = quotedLiteral.ReplaceAllString(sql, "?")
+	sql = numericLiteral.ReplaceAllString(sql, "?")
+	sql = strings.ReplaceAll(sql, "$?", "$n")
+	sql = placeholders.ReplaceAllString(sql, "(...)")
+	return strings.TrimSpace(whitespace.ReplaceAllString(sql, " "))
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *Tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, &trace{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if tr, ok := ctx.Value(traceKey{}).(*trace); ok {
+		t.record(tr.sql, tr.args, time.Since(tr.start), data.CommandTag.RowsAffected(), data.Err)
+	}
+}
+
+// TraceBatchStart implements pgx.BatchTracer.
+func (t *Tracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceBatchStartData) context.Context {
+	now := time.Now()
+	return context.WithValue(ctx, traceKey{}, &trace{start: now, last: now})
+}
+
+// TraceBatchQuery implements pgx.BatchTracer. Queries of a batch are
+// pipelined, so each one is timed from the end of the previous one.
+func (t *Tracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if tr, ok := ctx.Value(traceKey{}).(*trace); ok {
+		now := time.Now()
+		t.record(data.SQL, data.Args, now.Sub(tr.last), data.CommandTag.RowsAffected(), data.Err)
+		tr.last = now
+	}
+}
+
+// TraceBatchEnd implements pgx.BatchTracer.
+func (t *Tracer) TraceBatchEnd(context.Context, *pgx.Conn, pgx.TraceBatchEndData) {}
+
+// TraceCopyFromStart implements pgx.CopyFromTracer.
+func (t *Tracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	sql := fmt.Sprintf("COPY %s (%s) FROM STDIN", data.TableName.Sanitize(), strings.Join(data.ColumnNames, ", "))
+	return context.WithValue(ctx, traceKey{}, &trace{sql: sql, start: time.Now()})
+}
+
+// TraceCopyFromEnd implements pgx.CopyFromTracer.
+func (t *Tracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	if tr, ok := ctx.Value(traceKey{}).(*trace); ok {
+		t.record(tr.sql, nil, time.Since(tr.start), data.CommandTag.RowsAffected(), data.Err)
+	}
+}
+
+func (t *Tracer) record(sql string, args []any, duration time.Duration, rows int64, err error) {
+	fingerprint := Fingerprint(sql)
+
+	t.mu.Lock()
+	s, ok := t.stats[fingerprint]
+	if !ok {
+		if len(t.stats) >= maxFingerprints {
+			fingerprint = otherQueries
+			s = t.stats[otherQueries]
+		}
+		if s == nil {
+			s = &QueryStats{Fingerprint: fingerprint}
+			t.stats[fingerprint] = s
+		}
+	}
+	s.Calls++
+	s.RowsAffected += rows
+	s.TotalDuration += duration
+	if duration > s.MaxDuration {
+		s.MaxDuration = duration
+	}
+	if err != nil {
+		s.Errors++
+	}
+	t.mu.Unlock()
+
+	if threshold := time.Duration(t.slowThreshold.Load()); threshold > 0 && duration >= threshold {
+		logger.Logger.Warnf("Slow query (%s, %d rows): %s args=%s", duration, rows, Fingerprint(sql), redactArgs(args))
+	}
+}
+
+// redactArgs describes args by type only, e.g. [$1=string $2=int64].
+func redactArgs(args []any) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = fmt.Sprintf("$%d=%T", i+1, arg)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// Stats returns the statistics of every fingerprint, slowest in total first.
+func (t *Tracer) Stats() []QueryStats {
+	t.mu.Lock()
+	stats := make([]QueryStats, 0, len(t.stats))
+	for _, s := range t.stats {
+		stats = append(stats, *s)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].TotalDuration > stats[j].TotalDuration })
+	return stats
+}
+
+// Reset discards the collected statistics.
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	t.stats = map[string]*QueryStats{}
+	t.mu.Unlock()
+}
+
+// metricFamilies are the metrics MetricsHandler exposes for every query.
+var metricFamilies = []struct {
+	name, kind string
+	value      func(s QueryStats) string
+}{
+	{"db_query_calls_total", "counter", func(s QueryStats) string { return fmt.Sprint(s.Calls) }},
+	{"db_query_errors_total", "counter", func(s QueryStats) string { return fmt.Sprint(s.Errors) }},
+	{"db_query_rows_affected_total", "counter", func(s QueryStats) string { return fmt.Sprint(s.RowsAffected) }},
+	{"db_query_duration_seconds_total", "counter", func(s QueryStats) string { return fmt.Sprintf("%g", s.TotalDuration.Seconds()) }},
+	{"db_query_duration_seconds_max", "gauge", func(s QueryStats) string { return fmt.Sprintf("%g", s.MaxDuration.Seconds()) }},
+}
+
+// labelEscaper escapes a Prometheus label value.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// MetricsHandler serves the statistics in the Prometheus text format, so they
+// can be scraped. The samples of each metric are written together after its
+// TYPE line, as the format requires:
+//
+//	router.GET("/metrics/database", database.Queries.MetricsHandler())
+func (t *Tracer) MetricsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats := t.Stats()
+		var b strings.Builder
+		for _, family := range metricFamilies {
+			fmt.Fprintf(&b, "# TYPE %s %s\n", family.name, family.kind)
+			for _, s := range stats {
+				fmt.Fprintf(&b, "%s{query=\"%s\"} %s\n", family.name, labelEscaper.Replace(s.Fingerprint), family.value(s))
+			}
+		}
+		c.Data(http.StatusOK, "text/plain; version=0.0.4", []byte(b.String()))
+	}
+}
@@ -0,0 +1,144 @@
+// Package health provides a registry of health checks shared by every
+// component of a service (database, RabbitMQ clients...) and gin handlers to
+// expose them as liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Status is the health of a component or of the whole service.
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// DefaultTimeout bounds how long ReadinessHandler waits for all checks.
+const DefaultTimeout = 3 * time.Second
+
+// Check is the result of a single health check.
+//
+// Fields:
+//   - Status: Whether the component is up or down.
+//   - Latency: How long the check took.
+//   - Details: Component specific figures (pool statistics, versions...).
+//   - Error: The reason the component is down.
+type Check struct {
+	Status  Status         `json:"status"`
+	Latency string         `json:"latency,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Report aggregates every registered Check. Status is down if any check is down.
+type Report struct {
+	Status Status           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+// Checker is implemented by components that can report their health.
+type Checker interface {
+	CheckHealth(ctx context.Context) Check
+}
+
+// CheckerFunc adapts a function into a Checker.
+type CheckerFunc func(ctx context.Context) Check
+
+func (f CheckerFunc) CheckHealth(ctx context.Context) Check { return f(ctx) }
+
+var (
+	mu       sync.RWMutex
+	checkers = map[string]Checker{}
+)
+
+// Register adds checker to the registry under name, replacing any checker
+// previously registered with the same name.
+func Register(name string, checker Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+	checkers[name] = checker
+}
+
+// Unregister removes the checker registered under name.
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(checkers, name)
+}
+
+// Run executes every registered check concurrently and aggregates the results.
+func Run(ctx context.Context) Report {
+	mu.RLock()
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	snapshot := make([]Checker, len(names))
+	for i, name := range names {
+		snapshot[i] = checkers[name]
+	}
+	mu.RUnlock()
+
+	results := make([]Check, len(names))
+	var wg sync.WaitGroup
+	for i, checker := range snapshot {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			start := time.Now()
+			check := checker.CheckHealth(ctx)
+			if check.Latency == "" {
+				check.Latency = time.Since(start).String()
+			}
+			results[i] = check
+		}(i, checker)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Check, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// LivenessHandler answers 200 as long as the process is able to serve requests.
+func LivenessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": StatusUp})
+	}
+}
+
+// ReadinessHandler runs every registered check and answers 200 when all of
+// them are up or 503 otherwise, with the Report as body.
+func ReadinessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), DefaultTimeout)
+		defer cancel()
+
+		report := Run(ctx)
+		status := http.StatusOK
+		if report.Status != StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		c.IndentedJSON(status, report)
+	}
+}
+
+// RegisterRoutes mounts GET /health/live and GET /health/ready on r.
+func RegisterRoutes(r gin.IRouter) {
+	r.GET("/health/live", LivenessHandler())
+	r.GET("/health/ready", ReadinessHandler())
+}
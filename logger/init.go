@@ -7,7 +7,39 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// InitLogger initializes Logger. When remote is true, logs and stats are also
+// published to RabbitMQ using the connection settings from
+// rabbitmq.ConfigFromEnv; use InitRemoteLogger to pass them explicitly.
 func InitLogger(name string, logLevel LogLevel, output io.Writer, remote bool) error {
+	if !remote {
+		return initLogger(name, logLevel, output, nil)
+	}
+	config, err := rabbitmq.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	return InitRemoteLogger(name, logLevel, output, config)
+}
+
+// InitRemoteLogger initializes Logger publishing logs and stats to the broker
+// described by config.
+func InitRemoteLogger(name string, logLevel LogLevel, output io.Writer, config rabbitmq.Config) error {
+	return initLogger(name, logLevel, output, func(l *logger) error {
+		return l.connectRabbitMQ(config)
+	})
+}
+
+// InitLoggerWithClient initializes Logger publishing logs and stats with
+// client, which must have declared the LogExchangeName and StatsExchangeName
+// exchanges. Tests can pass a rabbitmq.FakeBroker client to assert on them.
+func InitLoggerWithClient(name string, logLevel LogLevel, output io.Writer, client rabbitmq.ClientI) error {
+	return initLogger(name, logLevel, output, func(l *logger) error {
+		l.useRabbitMQ(client)
+		return nil
+	})
+}
+
+func initLogger(name string, logLevel LogLevel, output io.Writer, connect func(l *logger) error) error {
 	logrus_instance := logrus.New()
 
 	logrus_instance.SetFormatter(&logrus.TextFormatter{
@@ -27,11 +59,8 @@ func InitLogger(name string, logLevel LogLevel, output io.Writer, remote bool) e
 	if err != nil {
 		return err
 	}
-	if remote {
-		err = l.connectRabbitMQ(rabbitmq.Config{
-			Host: "rabbitmq",
-			Port: 5672,
-		})
+	if connect != nil {
+		err = connect(l)
 		if err != nil {
 			return err
 		}
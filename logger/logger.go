@@ -3,6 +3,7 @@ package logger
 import (
 	"fmt"
 
+	"github.com/Class-Connect-GRUPO-5/microservices-common/health"
 	"github.com/Class-Connect-GRUPO-5/microservices-common/logger/events"
 	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -34,37 +35,78 @@ type logger struct {
 	name     string
 	level    LogLevel
 	logrus   *logrus.Logger
-	rabbitmq rabbitmq.Client
+	rabbitmq rabbitmq.ClientI
 }
 
+// Messages are published with routing keys logs.<level> (e.g. logs.error) and
+// stats.<event type>, so consumers of topic exchanges can bind to a subset.
 const LogExchangeName = "logs"
 const StatsExchangeName = "stats"
 
 func (l *logger) connectRabbitMQ(config rabbitmq.Config) error {
 	exchanges := []string{LogExchangeName, StatsExchangeName}
+	if config.Logf == nil {
+		// Logged locally only: the remote exchange is what is failing.
+		config.Logf = func(format string, args ...any) {
+			l.logrusLog(Warn, fmt.Sprintf(format, args...))
+		}
+	}
 	c, err := rabbitmq.NewClient(l.name, config, exchanges)
 	if err != nil {
-		return fmt.Errorf("error connecting to rabbitmq")
+		return fmt.Errorf("error connecting to rabbitmq: %v", err)
 	}
-	l.rabbitmq = c
+	l.useRabbitMQ(c)
 	return nil
 }
 
+// useRabbitMQ publishes logs and stats with c, logging its state changes.
+func (l *logger) useRabbitMQ(c rabbitmq.ClientI) {
+	c.OnStateChange(func(state rabbitmq.State) {
+		// Logged locally only: the remote exchange is what is failing.
+		level := Warn
+		if state == rabbitmq.StateConnected {
+			level = Info
+		}
+		l.logrusLog(level, fmt.Sprintf("rabbitmq logs connection %s", state))
+	})
+	l.rabbitmq = c
+}
+
+// HealthCheckName is the name RegisterHealthCheck registers the logs
+// connection under in the health registry.
+const HealthCheckName = "rabbitmq.logs"
+
+// RegisterHealthCheck adds the RabbitMQ client publishing logs and stats to
+// the health registry, so /health/ready fails while it is disconnected. It is
+// opt in: most services should keep serving when only logging is down. It
+// does nothing when Logger does not publish to RabbitMQ.
+func RegisterHealthCheck() {
+	if l, ok := Logger.(*logger); ok && l.rabbitmq != nil {
+		health.Register(HealthCheckName, l.rabbitmq)
+	}
+}
+
 func (l *logger) Log(level LogLevel, msg string) {
 	l.logrusLog(level, msg)
+	if l.rabbitmq == nil {
+		return
+	}
 
-	err := l.rabbitmq.Send(LogExchangeName, amqp.Table{"level": level.String()}, []byte(msg))
+	err := l.rabbitmq.SendWithKey(LogExchangeName, LogExchangeName+"."+level.String(), amqp.Table{"level": level.String()}, []byte(msg))
 	if err != nil {
 		l.logrusLog(Error, fmt.Sprintf("failed to emit event to rabbitMQ: %v", err))
 	}
 }
 
 func (l *logger) Emit(event events.Event) {
+	if l.rabbitmq == nil {
+		return
+	}
 	b, err := event.Encode()
 	if err != nil {
 		panic(err)
 	}
-	l.rabbitmq.Send(StatsExchangeName, amqp.Table{"type": event.Type()}, b)
+	l.rabbitmq.SendWithKey(StatsExchangeName, StatsExchangeName+"."+event.Type(), amqp.Table{"type": event.Type()}, b)
 }
 
 func (l *logger) logrusLog(level LogLevel, msg string) {
@@ -13,6 +13,8 @@ import (
 )
 
 // RequireRole is a middleware that checks if the user has the required role.
+// It responds 401 when the token is missing, invalid or expired, and 403 when
+// the token is valid but its role or user ID does not grant access.
 func RequireRole(jwtSecret string, isIDRequired bool, requiredRoles []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims, err := ExtractUserJWT(c, jwtSecret)
@@ -48,7 +50,7 @@ func RequireRole(jwtSecret string, isIDRequired bool, requiredRoles []string) gi
 
 		if !roleMatched {
 			logger.Logger.Warnf("Access denied for user %v with role %v", claims["user_id"], role)
-			utils.HandleError(c, http.StatusUnauthorized, "Unauthorized", fmt.Errorf("access denied").Error())
+			utils.HandleError(c, http.StatusForbidden, "Forbidden", fmt.Errorf("access denied").Error())
 			c.Abort()
 			return
 		}
@@ -58,7 +60,7 @@ func RequireRole(jwtSecret string, isIDRequired bool, requiredRoles []string) gi
 			reqUserID := c.Param("id_user")
 			if userID != reqUserID {
 				logger.Logger.Warnf("User ID mismatch: token user ID %v, request user ID %v", userID, reqUserID)
-				utils.HandleError(c, http.StatusUnauthorized, "Unauthorized", fmt.Errorf("user ID mismatch").Error())
+				utils.HandleError(c, http.StatusForbidden, "Forbidden", fmt.Errorf("user ID mismatch").Error())
 				c.Abort()
 				return
 			}
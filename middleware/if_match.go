@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// IfMatch returns a middleware that reads the If-Match header and stores the
+// version it carries under repository.ExpectedVersionKey, so Repository.UpdateCtx,
+// given utils.RequestContext(c), only applies the update if the row is still
+// at that version and answers 412 Precondition Failed otherwise. Requests without If-Match, or with
+// "If-Match: *" (any current version), pass through without a version check.
+func IfMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := strings.TrimSpace(c.GetHeader("If-Match"))
+		if header == "" || header == "*" {
+			c.Next()
+			return
+		}
+
+		version, err := utils.ParseETag(header)
+		if err != nil {
+			logger.Logger.Warnf("Invalid If-Match header: %s", header)
+			utils.HandleError(c, http.StatusBadRequest, err.Error(), c.Request.URL.Path)
+			c.Abort()
+			return
+		}
+
+		c.Set(repository.ExpectedVersionKey, version)
+		c.Next()
+	}
+}
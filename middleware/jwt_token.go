@@ -82,6 +82,7 @@ func SetJWTDataFromToken(secret string) gin.HandlerFunc {
 		logger.Logger.Debug(fmt.Sprintf("Parsed JWT data: %+v", jwtData))
 
 		ctx.Set("userData", jwtData)
+		ctx.Set("user_id", userID)
 		ctx.Next()
 	}
 }
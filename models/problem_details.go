@@ -57,3 +57,23 @@ func Forbidden(detail, instance string) ProblemDetails {
 func Unauthorized(detail, instance string) ProblemDetails {
 	return NewProblemDetails(http.StatusUnauthorized, "Unauthorized", detail, instance)
 }
+
+// StatusClientClosedRequest is the non-standard status code (popularized by nginx)
+// used when the client cancelled the request before the server could answer.
+const StatusClientClosedRequest = 499
+
+func GatewayTimeout(detail, instance string) ProblemDetails {
+	return NewProblemDetails(http.StatusGatewayTimeout, "Gateway Timeout", detail, instance)
+}
+
+func ClientClosedRequest(detail, instance string) ProblemDetails {
+	return NewProblemDetails(StatusClientClosedRequest, "Client Closed Request", detail, instance)
+}
+
+func Conflict(detail, instance string) ProblemDetails {
+	return NewProblemDetails(http.StatusConflict, "Conflict", detail, instance)
+}
+
+func PreconditionFailed(detail, instance string) ProblemDetails {
+	return NewProblemDetails(http.StatusPreconditionFailed, "Precondition Failed", detail, instance)
+}
@@ -12,13 +12,31 @@ package models
 //   - Message: A detailed explanation of the successful operation.
 //   - Instance: A URI reference that identifies the specific occurrence of the success.
 //   - Data: A stringified JSON representing the payload.
+//   - Page: Pagination metadata, only present on paged listings.
 type SuccessDetails struct {
-	Type     string `json:"type"`
-	Title    string `json:"title"`
-	Status   int    `json:"status"`
-	Message  string `json:"message"`
-	Instance string `json:"instance"`
-	Data     string `json:"data"`
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Status   int       `json:"status"`
+	Message  string    `json:"message"`
+	Instance string    `json:"instance"`
+	Data     string    `json:"data"`
+	Page     *PageInfo `json:"page,omitempty"`
+}
+
+// PageInfo describes the page of results carried by a SuccessDetails.
+//
+// Fields:
+//   - Limit: The maximum number of items requested for the page.
+//   - Offset: The number of items skipped (offset pagination only).
+//   - Total: The total number of items matching the query.
+//   - NextCursor: An opaque cursor to request the following page (keyset pagination only).
+//   - HasMore: Whether there are more items after this page.
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
 }
 
 // NewSuccessDetails creates a new SuccessDetails instance with the given parameters.
@@ -44,6 +62,14 @@ func NewSuccessDetails(status int, title, message, instance, data string) Succes
 	}
 }
 
+// NewPagedSuccessDetails creates a SuccessDetails carrying one page of results
+// along with its pagination metadata.
+func NewPagedSuccessDetails(status int, title, message, instance, data string, page PageInfo) SuccessDetails {
+	s := NewSuccessDetails(status, title, message, instance, data)
+	s.Page = &page
+	return s
+}
+
 // GetStatus returns the HTTP status code.
 func (s SuccessDetails) GetStatus() int { return s.Status }
 
@@ -3,14 +3,19 @@ package notifications
 import (
 	"fmt"
 
+	"github.com/Class-Connect-GRUPO-5/microservices-common/health"
 	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
 	"github.com/rabbitmq/amqp091-go"
 )
 
 const NotificationsExchangeName = "notifications"
 
+// NotificationRoutingKeyPrefix prefixes the notification type in the routing
+// key of every notification, e.g. notification.NewTask.
+const NotificationRoutingKeyPrefix = "notification."
+
 type notificationClient struct {
-	rabbitmqClient rabbitmq.Client
+	rabbitmqClient rabbitmq.ClientI
 }
 
 var client *notificationClient
@@ -23,7 +28,8 @@ func Send(userId string, notification Notification) error {
 	if err != nil {
 		return fmt.Errorf("error encoding notification: %s", err)
 	}
-	return client.rabbitmqClient.Send(NotificationsExchangeName, amqp091.Table{"type": notification.Type(), "user": userId}, body)
+	key := NotificationRoutingKeyPrefix + notification.Type()
+	return client.rabbitmqClient.SendWithKey(NotificationsExchangeName, key, amqp091.Table{"type": notification.Type(), "user": userId}, body)
 }
 
 type Config struct {
@@ -31,13 +37,44 @@ type Config struct {
 	Rabbitmq    rabbitmq.Config
 }
 
+// ConfigFromEnv returns the Config of serviceName with the connection settings
+// read by rabbitmq.ConfigFromEnv.
+func ConfigFromEnv(serviceName string) (Config, error) {
+	rabbitmqConfig, err := rabbitmq.ConfigFromEnv()
+	if err != nil {
+		return Config{}, err
+	}
+	return Config{ServiceName: serviceName, Rabbitmq: rabbitmqConfig}, nil
+}
+
 func Init(config Config) error {
 	rabbitmqClient, err := rabbitmq.NewClient(config.ServiceName, config.Rabbitmq, []string{NotificationsExchangeName})
 	if err != nil {
 		return fmt.Errorf("error connecting to rabbitmq: %s", err)
 	}
+	InitWithClient(rabbitmqClient)
+	return nil
+}
+
+// InitWithClient sends notifications with rabbitmqClient, which must have
+// declared the NotificationsExchangeName exchange. Tests can pass a
+// rabbitmq.FakeBroker client to assert on the notifications sent.
+func InitWithClient(rabbitmqClient rabbitmq.ClientI) {
 	client = &notificationClient{
 		rabbitmqClient: rabbitmqClient,
 	}
-	return nil
+}
+
+// HealthCheckName is the name RegisterHealthCheck registers the notifications
+// connection under in the health registry.
+const HealthCheckName = "rabbitmq.notifications"
+
+// RegisterHealthCheck adds the RabbitMQ client sending notifications to the
+// health registry, so /health/ready fails while it is disconnected. It is opt
+// in, for services that cannot work without sending notifications. It does
+// nothing before Init.
+func RegisterHealthCheck() {
+	if client != nil {
+		health.Register(HealthCheckName, client.rabbitmqClient)
+	}
 }
@@ -1,74 +1,372 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Class-Connect-GRUPO-5/microservices-common/health"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-type Config struct {
-	Host string
-	Port uint16
+const (
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+// ErrNotConnected is returned by Send while the client is reconnecting or
+// after it was closed.
+var ErrNotConnected = errors.New("rabbitmq client not connected")
+
+// Exchange describes how an exchange is declared.
+//
+// Fields:
+//   - Name: The exchange name.
+//   - Kind: amqp.ExchangeFanout (the default), amqp.ExchangeTopic,
+//     amqp.ExchangeDirect or amqp.ExchangeHeaders.
+//   - Durable, AutoDelete: Whether it survives a broker restart and whether it
+//     is deleted once no queue is bound to it.
+//   - Args: Optional declaration arguments, e.g. alternate-exchange.
+//
+// Redeclaring an existing exchange with different options fails, so changing
+// them requires deleting the exchange first.
+type Exchange struct {
+	Name       string
+	Kind       string
+	Durable    bool
+	AutoDelete bool
+	Args       amqp.Table
+}
+
+// State is the connection state of a Client.
+type State int32
+
+const (
+	StateConnecting State = iota
+	StateConnected
+	StateReconnecting
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
 }
 
-func Url(c Config) string {
-	return fmt.Sprintf("amqp://guest:guest@%s:%d/", c.Host, c.Port)
+// ClientI is the part of Client used to publish, consume and make calls, so
+// code depending on it can be tested against a FakeBroker.
+type ClientI interface {
+	Send(exchange string, headers amqp.Table, body []byte) error
+	SendContext(ctx context.Context, exchange string, headers amqp.Table, body []byte) error
+	SendWithKey(exchange, key string, headers amqp.Table, body []byte) error
+	SendWithKeyContext(ctx context.Context, exchange, key string, headers amqp.Table, body []byte) error
+	Consume(ctx context.Context, exchange, queue string, handler Handler, options ConsumerOptions) error
+	Call(ctx context.Context, queue string, headers amqp.Table, body []byte) (amqp.Delivery, error)
+	Serve(ctx context.Context, queue string, handler RPCHandler, options ConsumerOptions) error
+	State() State
+	OnStateChange(fn func(State))
+	CheckHealth(ctx context.Context) health.Check
+	Close() error
 }
 
+var _ ClientI = (*Client)(nil)
+
+// Client publishes to a set of exchanges. It watches its connection and
+// channel and, when the broker closes either of them, reconnects with
+// exponential backoff and jitter and declares the exchanges again, so Send
+// recovers by itself after a broker restart. Publishes use a pool of channels
+// (see Config.Channels), so Send is safe for concurrent use.
 type Client struct {
-	name string
+	name      string
+	config    Config
+	exchanges []Exchange
+
+	mu   sync.RWMutex
 	conn *amqp.Connection
 	ch   *amqp.Channel
+
+	state     atomic.Int32
+	listeners []func(State)
+	returns   []func(amqp.Return)
+	drops     []func(DroppedMessage)
+	done      chan struct{}
+	closeOnce sync.Once
+
+	outbox outbox
+	pool   channelPool
+	rpc    rpc
 }
 
-func NewClient(name string, config Config, exchanges []string) (Client, error) {
-	var conn *amqp.Connection
-	for {
+// NewClient connects to the broker, retrying until it succeeds, and declares
+// exchanges. Use NewClientContext to be able to give up. It fails right away
+// when the broker refuses the connection or a declaration, e.g. with
+// PRECONDITION_FAILED when an exchange exists with other options.
+func NewClient(name string, config Config, exchanges []string) (*Client, error) {
+	return NewClientContext(context.Background(), name, config, exchanges)
+}
+
+// NewClientContext connects to the broker and declares exchanges, retrying
+// with exponential backoff until it succeeds or ctx is done, in which case it
+// returns the last connection error. Every failed attempt is reported to
+// Config.Logf. Once connected the client reconnects on its own until Close is
+// called; ctx only bounds the initial connection.
+func NewClientContext(ctx context.Context, name string, config Config, exchanges []string) (*Client, error) {
+	c := &Client{
+		name:      name,
+		config:    config,
+		exchanges: append([]Exchange{}, config.Exchanges...),
+		done:      make(chan struct{}),
+		outbox:    outbox{wake: make(chan struct{}, 1), turn: make(chan struct{}, 1)},
+		pool:      newChannelPool(config.Channels),
+	}
+	for _, exchangeName := range exchanges {
+		if _, ok := c.exchange(exchangeName); !ok {
+			c.exchanges = append(c.exchanges, Exchange{Name: exchangeName, Kind: amqp.ExchangeFanout})
+		}
+	}
+	if err := c.connectWithBackoff(ctx, true); err != nil {
+		c.setState(StateClosed)
+		return nil, err
+	}
+	go c.watch()
+	if config.OutboxSize > 0 {
+		go c.flushOutbox()
+	}
+	return c, nil
+}
+
+// connectWithBackoff retries connect until it succeeds, ctx is done or the
+// client is closed, logging every failed attempt. When initial is set it also
+// gives up if the broker refuses the connection, since retrying will not help;
+// once the client is in use it keeps retrying so an operator can fix it.
+func (r *Client) connectWithBackoff(ctx context.Context, initial bool) error {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := r.connect()
+		if err == nil {
+			return nil
+		}
+		if initial && refused(err) {
+			return fmt.Errorf("error connecting to %s: %w", r.config, err)
+		}
+
+		// Full jitter in [backoff/2, backoff) so clients restarted together
+		// do not reconnect in lockstep.
+		wait := backoff/2 + rand.N(backoff/2)
+		r.config.logf("rabbitmq %s: connection attempt %d failed, retrying in %s: %v", r.name, attempt, wait.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error connecting to %s: %w (%v)", r.config, ctx.Err(), err)
+		case <-r.done:
+			return ErrNotConnected
+		case <-time.After(wait):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// connect dials, opens a channel and declares the exchanges, reusing the
+// current connection if it is still open.
+func (r *Client) connect() error {
+	r.mu.RLock()
+	conn := r.conn
+	r.mu.RUnlock()
+
+	if conn == nil || conn.IsClosed() {
 		var err error
-		conn, err = amqp.Dial(Url(config))
-		if err != nil {
-		} else {
-			break
+		if conn, err = r.config.dial(r.name); err != nil {
+			return err
 		}
-		time.Sleep(time.Second * 5)
+		go r.watchBlocked(conn.NotifyBlocked(make(chan amqp.Blocking, 1)))
 	}
 	ch, err := conn.Channel()
 	if err != nil {
-		return Client{}, fmt.Errorf("error getting rabbit channel: %s", err)
+		conn.Close()
+		return fmt.Errorf("error getting rabbit channel: %s", err)
+	}
+	for _, exchange := range r.exchanges {
+		if err := declareExchange(ch, exchange); err != nil {
+			conn.Close()
+			return err
+		}
 	}
-	for _, exchangeName := range exchanges {
 
-		err = ch.ExchangeDeclare(
-			exchangeName,
-			"fanout",
-			false,
-			false,
-			false,
-			false,
-			nil,
-		)
-		if err != nil {
-			return Client{}, fmt.Errorf("failed to declare exchange: %v", err)
+	r.mu.Lock()
+	select {
+	case <-r.done:
+		// Closed while connecting.
+		r.mu.Unlock()
+		conn.Close()
+		return ErrNotConnected
+	default:
+	}
+	r.conn, r.ch = conn, ch
+	r.mu.Unlock()
+	r.setState(StateConnected)
+	r.outbox.notify()
+	return nil
+}
+
+// exchange returns the declaration options of the exchange name, or a
+// non-durable fanout exchange when it has none.
+func (r *Client) exchange(name string) (Exchange, bool) {
+	for _, exchange := range r.exchanges {
+		if exchange.Name == name {
+			return exchange, true
 		}
 	}
-	return Client{name, conn, ch}, nil
+	return Exchange{Name: name, Kind: amqp.ExchangeFanout}, false
 }
 
-func (r *Client) Send(exchange string, headers amqp.Table, body []byte) error {
-	if r.ch == nil {
-		return nil
+// refused reports whether err is a refusal of the broker (access refused, not
+// found, precondition failed...), which retrying will not fix. amqp091 marks
+// these channel level exceptions as recoverable, meaning the connection is
+// still usable with different parameters.
+func refused(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Recover
+}
+
+// declareExchange declares exchange on ch.
+func declareExchange(ch *amqp.Channel, exchange Exchange) error {
+	kind := exchange.Kind
+	if kind == "" {
+		kind = amqp.ExchangeFanout
 	}
-	headers["source"] = r.name
-	return r.ch.Publish(
-		exchange,
-		"",
+	err := ch.ExchangeDeclare(
+		exchange.Name,
+		kind,
+		exchange.Durable,
+		exchange.AutoDelete,
 		false,
 		false,
-		amqp.Publishing{
-			Headers:     headers,
-			ContentType: "text/plain",
-			Body:        body,
-		},
+		exchange.Args,
 	)
+	if err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
+	}
+	return nil
+}
+
+// channel opens a new channel on the current connection, for consumers that
+// need their own QoS.
+func (r *Client) channel() (*amqp.Channel, error) {
+	r.mu.RLock()
+	conn := r.conn
+	r.mu.RUnlock()
+	if conn == nil || conn.IsClosed() || r.State() != StateConnected {
+		return nil, ErrNotConnected
+	}
+	return conn.Channel()
+}
+
+// watch waits for the connection or the channel to close and reconnects,
+// until Close is called.
+func (r *Client) watch() {
+	for {
+		r.mu.RLock()
+		connClosed := r.conn.NotifyClose(make(chan *amqp.Error, 1))
+		chClosed := r.ch.NotifyClose(make(chan *amqp.Error, 1))
+		r.mu.RUnlock()
+
+		select {
+		case <-r.done:
+			return
+		case <-connClosed:
+		case <-chClosed:
+		}
+
+		r.setState(StateReconnecting)
+		if err := r.connectWithBackoff(context.Background(), false); err != nil {
+			return
+		}
+	}
+}
+
+func (r *Client) setState(state State) {
+	if State(r.state.Swap(int32(state))) == state {
+		return
+	}
+	r.mu.RLock()
+	listeners := r.listeners
+	r.mu.RUnlock()
+	for _, fn := range listeners {
+		fn(state)
+	}
+}
+
+// State returns the current connection state.
+func (r *Client) State() State {
+	return State(r.state.Load())
+}
+
+// OnStateChange registers fn to be called every time the state changes, e.g.
+// to log reconnections.
+func (r *Client) OnStateChange(fn func(State)) {
+	r.mu.Lock()
+	r.listeners = append(r.listeners, fn)
+	r.mu.Unlock()
+}
+
+// Close is Shutdown waiting at most 5 seconds for publishes to drain.
+func (r *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDrainTimeout)
+	defer cancel()
+	return r.Shutdown(ctx)
+}
+
+// Shutdown stops accepting publishes, waits until the outbox is flushed and
+// the publishes in flight are confirmed, or ctx is done, and then stops
+// reconnecting and closes the channels and the connection. It returns an
+// error if messages were left unpublished.
+func (r *Client) Shutdown(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	var err error
+	r.closeOnce.Do(func() {
+		drainErr := r.drain(ctx)
+		close(r.done)
+		r.setState(StateClosed)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.ch != nil {
+			r.ch.Close()
+		}
+		if r.conn != nil && !r.conn.IsClosed() {
+			err = r.conn.Close()
+		}
+		err = errors.Join(drainErr, err)
+	})
+	return err
+}
+
+// CheckHealth reports the connection state, so the client can be added to
+// the health registry.
+func (r *Client) CheckHealth(ctx context.Context) health.Check {
+	if r == nil {
+		return health.Check{Status: health.StatusDown, Error: "client not initialized"}
+	}
+	state := r.State()
+	details := map[string]any{"client": r.name, "state": state.String(), "blocked": r.Blocked()}
+	if state != StateConnected {
+		return health.Check{Status: health.StatusDown, Details: details, Error: "connection " + state.String()}
+	}
+	return health.Check{Status: health.StatusUp, Details: details}
 }
@@ -0,0 +1,247 @@
+package rabbitmq
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	defaultPort    = 5672
+	defaultTLSPort = 5671
+)
+
+// Config holds the broker address, credentials and the publishing guarantees
+// of a Client.
+//
+// Fields:
+//   - Host, Port: The broker address. Port defaults to 5672, or 5671 with TLS.
+//   - User, Password: The credentials. Both default to "guest".
+//   - Vhost: The virtual host. Empty means the default vhost "/".
+//   - TLS: Connect with amqps://. It is implied when CACert or ClientCert is set.
+//   - CACert: Path to the PEM CA certificate used to verify the broker. Empty
+//     uses the system roots.
+//   - ClientCert, ClientKey: Paths to the PEM client certificate and key, for
+//     brokers that authenticate clients with certificates.
+//   - Heartbeat: The heartbeat interval negotiated with the broker. Zero keeps
+//     the library default (10s).
+//   - ConnectionName: Shown in the management UI. Defaults to the client name.
+//   - Confirm: Put the channel in confirm mode, so Send waits for the broker to
+//     ack each message and fails on nack or after ConfirmTimeout (default 5s).
+//   - Persistent: Publish with the persistent delivery mode, so messages that
+//     reach durable queues survive a broker restart.
+//   - Mandatory: Ask the broker to return messages no queue is bound to
+//     receive; they are handed to the OnReturn handlers.
+//   - OutboxSize: When positive, messages that could not be published (or
+//     confirmed) are kept in a local buffer of this size and retried in order
+//     in the background instead of failing Send. To keep that order, Sends
+//     are then published one at a time.
+//   - OutboxMaxAttempts: How many times a message is tried while connected
+//     before it is dropped from the outbox and handed to the OnDrop handlers,
+//     so a message the broker keeps rejecting does not hold back the ones
+//     behind it. Defaults to 5.
+//   - Channels: How many channels publish at once; further Sends wait for
+//     one to be free. Defaults to 4.
+//   - BlockedTimeout: How long Send waits while the broker throttles
+//     publishers (blocked connection or stopped channel flow) before failing
+//     with ErrBlocked. Defaults to 5s.
+//   - Exchanges: Declaration options of exchanges, by name. Exchanges listed
+//     here are declared on connect, and exchanges used without an entry are
+//     declared as non-durable fanout.
+//   - Logf: Receives every failed connection attempt and every delivery a
+//     consumer drops. Defaults to log.Printf.
+type Config struct {
+	Host     string
+	Port     uint16
+	User     string
+	Password string
+	Vhost    string
+
+	TLS        bool
+	CACert     string
+	ClientCert string
+	ClientKey  string
+
+	Heartbeat      time.Duration
+	ConnectionName string
+
+	Exchanges []Exchange
+
+	Confirm           bool
+	ConfirmTimeout    time.Duration
+	Persistent        bool
+	Mandatory         bool
+	OutboxSize        int
+	OutboxMaxAttempts int
+
+	Channels       int
+	BlockedTimeout time.Duration
+
+	Logf func(format string, args ...any)
+}
+
+// ConfigFromEnv reads the connection settings from environment variables,
+// defaulting to guest:guest@rabbitmq:5672 as used so far:
+//
+//   - RABBITMQ_HOST, RABBITMQ_PORT, RABBITMQ_USER, RABBITMQ_PASSWORD, RABBITMQ_VHOST
+//   - RABBITMQ_TLS (boolean), RABBITMQ_CA_CERT, RABBITMQ_CLIENT_CERT, RABBITMQ_CLIENT_KEY (file paths)
+//   - RABBITMQ_HEARTBEAT (duration such as "10s"), RABBITMQ_CONNECTION_NAME
+//
+// It returns an error if the port, TLS flag or heartbeat cannot be parsed.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Host:           envString("RABBITMQ_HOST", "rabbitmq"),
+		User:           os.Getenv("RABBITMQ_USER"),
+		Password:       os.Getenv("RABBITMQ_PASSWORD"),
+		Vhost:          os.Getenv("RABBITMQ_VHOST"),
+		CACert:         os.Getenv("RABBITMQ_CA_CERT"),
+		ClientCert:     os.Getenv("RABBITMQ_CLIENT_CERT"),
+		ClientKey:      os.Getenv("RABBITMQ_CLIENT_KEY"),
+		ConnectionName: os.Getenv("RABBITMQ_CONNECTION_NAME"),
+	}
+
+	if v := os.Getenv("RABBITMQ_PORT"); v != "" {
+		port, err := strconv.ParseUint(v, 10, 16)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RABBITMQ_PORT: %v", err)
+		}
+		cfg.Port = uint16(port)
+	}
+	if v := os.Getenv("RABBITMQ_TLS"); v != "" {
+		tls, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RABBITMQ_TLS: %v", err)
+		}
+		cfg.TLS = tls
+	}
+	if v := os.Getenv("RABBITMQ_HEARTBEAT"); v != "" {
+		heartbeat, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid RABBITMQ_HEARTBEAT: %v", err)
+		}
+		cfg.Heartbeat = heartbeat
+	}
+	return cfg, nil
+}
+
+func envString(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func (c Config) secure() bool {
+	return c.TLS || c.CACert != "" || c.ClientCert != ""
+}
+
+func (c Config) url() *url.URL {
+	scheme, port := "amqp", c.Port
+	if c.secure() {
+		scheme = "amqps"
+	}
+	if port == 0 {
+		port = defaultPort
+		if c.secure() {
+			port = defaultTLSPort
+		}
+	}
+	user, password := c.User, c.Password
+	if user == "" && password == "" {
+		user, password = "guest", "guest"
+	}
+	// The path "/" selects the default vhost; any other vhost is escaped so a
+	// "/" in its name is not taken as a separator.
+	path, rawPath := "/", "/"
+	if c.Vhost != "" && c.Vhost != "/" {
+		path, rawPath = "/"+c.Vhost, "/"+url.PathEscape(c.Vhost)
+	}
+	return &url.URL{
+		Scheme:  scheme,
+		User:    url.UserPassword(user, password),
+		Host:    net.JoinHostPort(c.Host, strconv.Itoa(int(port))),
+		Path:    path,
+		RawPath: rawPath,
+	}
+}
+
+// Url returns the AMQP URI of c, including the password. Use c.String to log
+// it.
+func Url(c Config) string {
+	return c.url().String()
+}
+
+// String returns the AMQP URI with the password redacted.
+func (c Config) String() string {
+	return c.url().Redacted()
+}
+
+// TLSConfig builds the TLS settings from CACert, ClientCert and ClientKey. It
+// returns nil when c does not use TLS.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	if !c.secure() {
+		return nil, nil
+	}
+	config := &tls.Config{ServerName: c.Host, MinVersion: tls.VersionTLS12}
+	if c.CACert != "" {
+		pem, err := os.ReadFile(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading rabbitmq CA certificate: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CACert)
+		}
+	}
+	if c.ClientCert != "" || c.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading rabbitmq client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// dial opens a connection with c, reported to the broker as name unless
+// ConnectionName is set.
+// logf reports a message with Logf, or log.Printf when it is not set.
+func (c Config) logf(format string, args ...any) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (c Config) dial(name string) (*amqp.Connection, error) {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	connectionName := c.ConnectionName
+	if connectionName == "" {
+		connectionName = name
+	}
+	properties := amqp.NewConnectionProperties()
+	properties.SetClientConnectionName(connectionName)
+
+	conn, err := amqp.DialConfig(Url(c), amqp.Config{
+		Heartbeat:       c.Heartbeat,
+		TLSClientConfig: tlsConfig,
+		Properties:      properties,
+		Locale:          "en_US",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error dialing %s: %w", c, err)
+	}
+	return conn, nil
+}
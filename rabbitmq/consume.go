@@ -0,0 +1,321 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Handler processes a delivery. Returning nil acks it; returning an error
+// nacks it so it is delivered again (after a delay with a RetryPolicy),
+// unless the error is wrapped with Permanent. Without a RetryPolicy a delivery
+// is requeued once: if it fails again it is dead lettered with DeadLetter and
+// dropped otherwise, so a message that always fails does not loop forever.
+//
+// "Again" is told by the Redelivered flag, which does not count handler
+// attempts: the broker also sets it on deliveries that were in flight when a
+// consumer or connection went away, e.g. during a redeploy, so such a delivery
+// is given up on its first failure. Use a RetryPolicy, which counts attempts
+// in the attempts header, when every delivery must be tried more than once.
+// Dropped deliveries are reported to Config.Logf.
+type Handler func(ctx context.Context, delivery amqp.Delivery) error
+
+// ConsumerOptions configures Consume.
+//
+// Fields:
+//   - Workers: How many deliveries are handled concurrently. Defaults to 1.
+//   - Prefetch: How many unacked deliveries the broker sends ahead (QoS).
+//     Defaults to Workers.
+//   - Durable, AutoDelete, Exclusive: Declaration flags of the queue. A queue
+//     named "" is always a server-named exclusive queue, so every instance of
+//     the service receives every message.
+//   - BindingKeys: Routing keys the queue is bound with. Defaults to "#" (every
+//     message) for topic exchanges and to "" otherwise.
+//   - DeadLetter: Declare a dead letter queue (see DeadLetterQueue) receiving
+//     the deliveries that fail with a Permanent error, fail again after being
+//     requeued or are rejected by the broker, instead of dropping them. The
+//     queue is then declared with the x-dead-letter-exchange argument, so a
+//     queue that already exists without it makes Consume fail with
+//     PRECONDITION_FAILED: delete it, or move its consumers to a new queue,
+//     before enabling DeadLetter or Retry.
+//   - Retry: Retry failed deliveries after a delay instead of requeueing them
+//     at once (see RetryPolicy). It implies DeadLetter.
+type ConsumerOptions struct {
+	Workers     int
+	Prefetch    int
+	Durable     bool
+	AutoDelete  bool
+	Exclusive   bool
+	BindingKeys []string
+	DeadLetter  bool
+	Retry       *RetryPolicy
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, e.g. a message that cannot be
+// decoded: the delivery is rejected instead of requeued.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+var consumerCount atomic.Uint64
+
+// Consume declares queue, binds it to exchange and handles its deliveries
+// with a pool of options.Workers goroutines until ctx is done. It survives
+// reconnections, setting the consumer up again once the client reconnects.
+//
+// On shutdown the consumer is cancelled, handlers already running finish, and
+// deliveries prefetched but not started are requeued. Consume then returns
+// nil; it returns an error only if the queue cannot be declared or bound.
+// With exchange "" the queue only receives the messages published to it by
+// name through the default exchange.
+//
+// Example:
+//
+//	go client.Consume(ctx, "notifications", "notifications.email", func(ctx context.Context, d amqp.Delivery) error {
+//	    return sendEmail(ctx, d.Body)
+//	}, rabbitmq.ConsumerOptions{Workers: 4, Durable: true})
+func (r *Client) Consume(ctx context.Context, exchange, queue string, handler Handler, options ConsumerOptions) error {
+	if r == nil {
+		return ErrNotConnected
+	}
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.Prefetch <= 0 {
+		options.Prefetch = options.Workers
+	}
+	if options.Retry != nil {
+		options.DeadLetter = true
+	}
+	if options.DeadLetter && queue == "" {
+		return errors.New("dead lettering and retries require a named queue")
+	}
+	tag := fmt.Sprintf("%s-%d", r.name, consumerCount.Add(1))
+
+	backoff := initialBackoff
+	for {
+		ch, deliveries, err := r.setupConsumer(exchange, queue, tag, options)
+		if refused(err) {
+			// A channel level refusal (access, not found, precondition failed
+			// on declare or bind): retrying will not help.
+			return fmt.Errorf("error consuming %s from %s: %w", queue, exchange, err)
+		}
+		if err == nil {
+			backoff = initialBackoff
+			c := consumer{ch: ch, exchange: exchange, queue: queue, handler: handler, options: options, logf: r.config.logf}
+			c.run(ctx, deliveries, tag)
+			ch.Close()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.done:
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// setupConsumer opens a channel, declares and binds the queue and starts
+// consuming from it.
+func (r *Client) setupConsumer(exchange, queue, tag string, options ConsumerOptions) (*amqp.Channel, <-chan amqp.Delivery, error) {
+	ch, err := r.channel()
+	if err != nil {
+		return nil, nil, err
+	}
+	fail := func(err error) (*amqp.Channel, <-chan amqp.Delivery, error) {
+		ch.Close()
+		return nil, nil, err
+	}
+
+	if err := ch.Qos(options.Prefetch, 0, false); err != nil {
+		return fail(err)
+	}
+	declaration, _ := r.exchange(exchange)
+	if exchange != "" {
+		if err := declareExchange(ch, declaration); err != nil {
+			return fail(err)
+		}
+	}
+	var args amqp.Table
+	if options.DeadLetter {
+		if args, err = declareRetryTopology(ch, queue, options); err != nil {
+			return fail(err)
+		}
+		// Retries and dead letters are confirmed before the original is acked.
+		if err := ch.Confirm(false); err != nil {
+			return fail(err)
+		}
+	}
+	exclusive := options.Exclusive || queue == ""
+	q, err := ch.QueueDeclare(queue, options.Durable && queue != "", options.AutoDelete, exclusive, false, args)
+	if err != nil {
+		return fail(err)
+	}
+	keys := options.BindingKeys
+	if exchange == "" {
+		// The default exchange routes by queue name and cannot be bound.
+		keys = nil
+	} else if len(keys) == 0 {
+		keys = []string{""}
+		if declaration.Kind == amqp.ExchangeTopic {
+			keys = []string{"#"}
+		}
+	}
+	for _, key := range keys {
+		if err := ch.QueueBind(q.Name, key, exchange, false, nil); err != nil {
+			return fail(err)
+		}
+	}
+	deliveries, err := ch.Consume(q.Name, tag, false, exclusive, false, false, nil)
+	if err != nil {
+		return fail(err)
+	}
+	return ch, deliveries, nil
+}
+
+// consumer handles the deliveries of one Consume call on one channel.
+type consumer struct {
+	ch       *amqp.Channel
+	exchange string
+	queue    string
+	handler  Handler
+	options  ConsumerOptions
+	logf     func(format string, args ...any)
+}
+
+// run handles deliveries with the worker pool until the channel closes or
+// ctx is done.
+func (c consumer) run(ctx context.Context, deliveries <-chan amqp.Delivery, tag string) {
+	stop := context.AfterFunc(ctx, func() {
+		// Stops new deliveries; the channel is closed once the broker confirms.
+		c.ch.Cancel(tag, false)
+	})
+	defer stop()
+
+	handlerCtx := context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	for i := 0; i < c.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range deliveries {
+				if ctx.Err() != nil {
+					d.Nack(false, true)
+					continue
+				}
+				c.handle(handlerCtx, d)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// outcome is what a consumer does with a delivery once its handler returned.
+type outcome int
+
+const (
+	outcomeAck outcome = iota
+	outcomeRequeue
+	outcomeRetry
+	outcomeDeadLetter
+	outcomeDrop
+)
+
+// settlement decides the outcome of d given the error its handler returned.
+// Client and FakeClient consumers both follow it.
+func settlement(d amqp.Delivery, err error, options ConsumerOptions) outcome {
+	switch {
+	case err == nil:
+		return outcomeAck
+	case IsPermanent(err) && options.DeadLetter:
+		return outcomeDeadLetter
+	case IsPermanent(err):
+		return outcomeDrop
+	case options.Retry != nil:
+		if Attempts(d)+1 >= options.Retry.maxAttempts() {
+			return outcomeDeadLetter
+		}
+		return outcomeRetry
+	case !d.Redelivered:
+		return outcomeRequeue
+	case options.DeadLetter:
+		return outcomeDeadLetter
+	default:
+		// Failed again after being requeued: give up instead of looping.
+		return outcomeDrop
+	}
+}
+
+// handle runs the handler on d and acks it, retries it or dead letters it. A
+// panicking handler is treated as a failed one.
+func (c consumer) handle(ctx context.Context, d amqp.Delivery) {
+	err := callHandler(ctx, c.handler, d)
+	switch settlement(d, err, c.options) {
+	case outcomeAck:
+		d.Ack(false)
+	case outcomeRequeue:
+		d.Nack(false, true)
+	case outcomeRetry:
+		c.settle(d, c.retry(ctx, d, Attempts(d)+1, err))
+	case outcomeDeadLetter:
+		c.settle(d, c.deadLetter(ctx, d, err))
+	case outcomeDrop:
+		c.logf("rabbitmq: dropping message %s from %s: %v", deliveryID(d), c.queue, err)
+		d.Nack(false, false)
+	}
+}
+
+// deliveryID identifies d in logs by its message ID, or its delivery tag when
+// it has none.
+func deliveryID(d amqp.Delivery) string {
+	if d.MessageId != "" {
+		return d.MessageId
+	}
+	return fmt.Sprintf("#%d", d.DeliveryTag)
+}
+
+// settle acks d once its copy was published, or requeues it if that failed.
+func (c consumer) settle(d amqp.Delivery, publishErr error) {
+	if publishErr != nil {
+		d.Nack(false, true)
+		return
+	}
+	d.Ack(false)
+}
+
+// callHandler runs handler on d, turning a panic into an error.
+func callHandler(ctx context.Context, handler Handler, d amqp.Delivery) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+	}()
+	return handler(ctx, d)
+}
@@ -0,0 +1,587 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/health"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// FakeBroker is an in-memory broker for unit tests. It routes messages from
+// exchanges to the queues bound to them following the rules of their kind
+// (fanout, direct and topic; headers exchanges behave as fanout), delivers
+// them to consumers and answers calls, so code using a ClientI can be tested
+// without a running RabbitMQ.
+//
+// Retry delays, message TTLs and the durability flags are not emulated:
+// failed deliveries under a RetryPolicy are retried at once.
+//
+// Example:
+//
+//	broker := rabbitmq.NewFakeBroker()
+//	client := broker.NewClient("users", rabbitmq.Config{}, []string{notifications.NotificationsExchangeName})
+//	notifications.InitWithClient(client)
+//	...
+//	assert.Len(t, broker.PublishedTo(notifications.NotificationsExchangeName), 1)
+type FakeBroker struct {
+	mu        sync.Mutex
+	exchanges map[string]Exchange
+	queues    map[string]*fakeQueue
+	bindings  []fakeBinding
+	published []Published
+	calls     map[string]chan rpcReply
+	queueSeq  int
+}
+
+// Published is a message published to a FakeBroker.
+type Published struct {
+	Exchange   string
+	RoutingKey string
+	amqp.Publishing
+}
+
+type fakeBinding struct {
+	exchange string
+	queue    string
+	key      string
+}
+
+// fakeQueue holds the messages ready to be delivered. ready is closed and
+// replaced whenever a message arrives, waking every waiting consumer.
+type fakeQueue struct {
+	messages []amqp.Delivery
+	ready    chan struct{}
+	tag      uint64
+}
+
+func (q *fakeQueue) push(d amqp.Delivery) {
+	q.tag++
+	d.DeliveryTag = q.tag
+	q.messages = append(q.messages, d)
+	close(q.ready)
+	q.ready = make(chan struct{})
+}
+
+// NewFakeBroker returns an empty broker.
+func NewFakeBroker() *FakeBroker {
+	return &FakeBroker{
+		exchanges: map[string]Exchange{},
+		queues:    map[string]*fakeQueue{},
+		calls:     map[string]chan rpcReply{},
+	}
+}
+
+// NewClient returns a connected client of the broker, declaring exchanges as
+// rabbitmq.NewClient does.
+func (b *FakeBroker) NewClient(name string, config Config, exchanges []string) *FakeClient {
+	c := &FakeClient{broker: b, name: name, config: config, done: make(chan struct{})}
+	c.state.Store(int32(StateConnected))
+	for _, exchange := range config.Exchanges {
+		b.DeclareExchange(exchange)
+	}
+	for _, exchange := range exchanges {
+		if _, ok := c.exchange(exchange); !ok {
+			b.DeclareExchange(Exchange{Name: exchange, Kind: amqp.ExchangeFanout})
+		}
+	}
+	return c
+}
+
+// DeclareExchange declares exchange, keeping the existing one if it was
+// already declared.
+func (b *FakeBroker) DeclareExchange(exchange Exchange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.declareExchange(exchange)
+}
+
+func (b *FakeBroker) declareExchange(exchange Exchange) {
+	if _, ok := b.exchanges[exchange.Name]; ok {
+		return
+	}
+	if exchange.Kind == "" {
+		exchange.Kind = amqp.ExchangeFanout
+	}
+	b.exchanges[exchange.Name] = exchange
+}
+
+// DeclareQueue declares queue if it does not exist yet.
+func (b *FakeBroker) DeclareQueue(queue string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.declareQueue(queue)
+}
+
+func (b *FakeBroker) declareQueue(queue string) string {
+	if queue == "" {
+		b.queueSeq++
+		queue = fmt.Sprintf("amq.gen-%d", b.queueSeq)
+	}
+	if _, ok := b.queues[queue]; !ok {
+		b.queues[queue] = &fakeQueue{ready: make(chan struct{})}
+	}
+	return queue
+}
+
+// Bind declares queue and binds it to exchange with key, so the messages
+// routed to it can be read with Messages.
+func (b *FakeBroker) Bind(queue, exchange, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bind(b.declareQueue(queue), exchange, key)
+}
+
+func (b *FakeBroker) bind(queue, exchange, key string) error {
+	if _, ok := b.exchanges[exchange]; !ok {
+		return fmt.Errorf("exchange %q not found", exchange)
+	}
+	for _, binding := range b.bindings {
+		if binding == (fakeBinding{exchange, queue, key}) {
+			return nil
+		}
+	}
+	b.bindings = append(b.bindings, fakeBinding{exchange, queue, key})
+	return nil
+}
+
+// Messages removes and returns the messages waiting in queue.
+func (b *FakeBroker) Messages(queue string) []amqp.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[queue]
+	if !ok {
+		return nil
+	}
+	messages := q.messages
+	q.messages = nil
+	return messages
+}
+
+// Published returns every message published so far, routed or not, in order.
+func (b *FakeBroker) Published() []Published {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Published{}, b.published...)
+}
+
+// PublishedTo returns the messages published to exchange, in order.
+func (b *FakeBroker) PublishedTo(exchange string) []Published {
+	var published []Published
+	for _, p := range b.Published() {
+		if p.Exchange == exchange {
+			published = append(published, p)
+		}
+	}
+	return published
+}
+
+// route returns the queues msg is delivered to. b.mu must be held.
+func (b *FakeBroker) route(exchange, key string) []*fakeQueue {
+	if exchange == "" {
+		if q, ok := b.queues[key]; ok {
+			return []*fakeQueue{q}
+		}
+		return nil
+	}
+	kind := b.exchanges[exchange].Kind
+	seen := map[string]bool{}
+	var queues []*fakeQueue
+	for _, binding := range b.bindings {
+		if binding.exchange != exchange || seen[binding.queue] {
+			continue
+		}
+		switch kind {
+		case amqp.ExchangeDirect:
+			if binding.key != key {
+				continue
+			}
+		case amqp.ExchangeTopic:
+			if !topicMatch(strings.Split(binding.key, "."), strings.Split(key, ".")) {
+				continue
+			}
+		}
+		seen[binding.queue] = true
+		queues = append(queues, b.queues[binding.queue])
+	}
+	return queues
+}
+
+// topicMatch reports whether the words of a routing key match a binding
+// pattern, where * stands for one word and # for zero or more.
+func topicMatch(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	if pattern[0] == "#" {
+		for i := 0; i <= len(words); i++ {
+			if topicMatch(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(words) == 0 || (pattern[0] != "*" && pattern[0] != words[0]) {
+		return false
+	}
+	return topicMatch(pattern[1:], words[1:])
+}
+
+// publish records msg and delivers it, reporting whether it was routed.
+func (b *FakeBroker) publish(msg message) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.exchanges[msg.exchange]; !ok && msg.exchange != "" {
+		return false, fmt.Errorf("exchange %q not found", msg.exchange)
+	}
+	b.published = append(b.published, Published{Exchange: msg.exchange, RoutingKey: msg.key, Publishing: msg.publishing})
+
+	if reply, ok := b.calls[msg.key]; ok && msg.exchange == "" {
+		delete(b.calls, msg.key)
+		reply <- rpcReply{delivery: fakeDelivery(msg)}
+		return true, nil
+	}
+	queues := b.route(msg.exchange, msg.key)
+	for _, q := range queues {
+		q.push(fakeDelivery(msg))
+	}
+	return len(queues) > 0, nil
+}
+
+func fakeDelivery(msg message) amqp.Delivery {
+	p := msg.publishing
+	return amqp.Delivery{
+		Acknowledger:    fakeAcknowledger{},
+		Headers:         p.Headers,
+		ContentType:     p.ContentType,
+		ContentEncoding: p.ContentEncoding,
+		DeliveryMode:    p.DeliveryMode,
+		Priority:        p.Priority,
+		CorrelationId:   p.CorrelationId,
+		ReplyTo:         p.ReplyTo,
+		Expiration:      p.Expiration,
+		MessageId:       p.MessageId,
+		Timestamp:       p.Timestamp,
+		Type:            p.Type,
+		AppId:           p.AppId,
+		Exchange:        msg.exchange,
+		RoutingKey:      msg.key,
+		Body:            p.Body,
+	}
+}
+
+// fakeAcknowledger lets handlers call Ack or Nack on fake deliveries, which
+// are settled by the consumer anyway.
+type fakeAcknowledger struct{}
+
+func (fakeAcknowledger) Ack(tag uint64, multiple bool) error                { return nil }
+func (fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error { return nil }
+func (fakeAcknowledger) Reject(tag uint64, requeue bool) error              { return nil }
+
+// FakeClient is a ClientI publishing to and consuming from a FakeBroker.
+type FakeClient struct {
+	broker *FakeBroker
+	name   string
+	config Config
+
+	mu        sync.Mutex
+	listeners []func(State)
+	returns   []func(amqp.Return)
+
+	state     atomic.Int32
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ ClientI = (*FakeClient)(nil)
+
+func (c *FakeClient) exchange(name string) (Exchange, bool) {
+	for _, exchange := range c.config.Exchanges {
+		if exchange.Name == name {
+			return exchange, true
+		}
+	}
+	return Exchange{Name: name, Kind: amqp.ExchangeFanout}, false
+}
+
+// Send publishes body to exchange, like Client.Send.
+func (c *FakeClient) Send(exchange string, headers amqp.Table, body []byte) error {
+	return c.SendWithKeyContext(context.Background(), exchange, "", headers, body)
+}
+
+// SendContext is Send bound to ctx.
+func (c *FakeClient) SendContext(ctx context.Context, exchange string, headers amqp.Table, body []byte) error {
+	return c.SendWithKeyContext(ctx, exchange, "", headers, body)
+}
+
+// SendWithKey is Send with a routing key.
+func (c *FakeClient) SendWithKey(exchange, key string, headers amqp.Table, body []byte) error {
+	return c.SendWithKeyContext(context.Background(), exchange, key, headers, body)
+}
+
+// SendWithKeyContext publishes to the broker. It fails with ErrNotConnected
+// after Close and when exchange was not declared, and hands unroutable
+// messages to the OnReturn handlers when Config.Mandatory is set.
+func (c *FakeClient) SendWithKeyContext(ctx context.Context, exchange, key string, headers amqp.Table, body []byte) error {
+	return c.publish(ctx, newMessage(c.name, c.config, exchange, key, headers, body))
+}
+
+func (c *FakeClient) publish(ctx context.Context, msg message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.State() != StateConnected {
+		return ErrNotConnected
+	}
+	routed, err := c.broker.publish(msg)
+	if err != nil || routed || !c.config.Mandatory {
+		return err
+	}
+	c.mu.Lock()
+	handlers := c.returns
+	c.mu.Unlock()
+	ret := amqp.Return{
+		ReplyCode:     amqp.NoRoute,
+		ReplyText:     "NO_ROUTE",
+		Exchange:      msg.exchange,
+		RoutingKey:    msg.key,
+		Headers:       msg.publishing.Headers,
+		CorrelationId: msg.publishing.CorrelationId,
+		Body:          msg.publishing.Body,
+	}
+	for _, fn := range handlers {
+		fn(ret)
+	}
+	return nil
+}
+
+// OnReturn registers fn to receive unroutable messages, see Client.OnReturn.
+func (c *FakeClient) OnReturn(fn func(amqp.Return)) {
+	c.mu.Lock()
+	c.returns = append(c.returns, fn)
+	c.mu.Unlock()
+}
+
+// Consume declares queue, binds it to exchange and handles its messages like
+// Client.Consume until ctx is done or the client is closed.
+func (c *FakeClient) Consume(ctx context.Context, exchange, queue string, handler Handler, options ConsumerOptions) error {
+	if c.State() != StateConnected {
+		return ErrNotConnected
+	}
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.Retry != nil {
+		options.DeadLetter = true
+	}
+	if options.DeadLetter && queue == "" {
+		return errors.New("dead lettering and retries require a named queue")
+	}
+
+	c.broker.mu.Lock()
+	declaration, _ := c.exchange(exchange)
+	queue = c.broker.declareQueue(queue)
+	if exchange != "" {
+		c.broker.declareExchange(declaration)
+		keys := options.BindingKeys
+		if len(keys) == 0 {
+			keys = []string{""}
+			if declaration.Kind == amqp.ExchangeTopic {
+				keys = []string{"#"}
+			}
+		}
+		for _, key := range keys {
+			c.broker.bind(queue, exchange, key)
+		}
+	}
+	if options.DeadLetter {
+		c.broker.declareExchange(Exchange{Name: DeadLetterExchange(queue), Kind: amqp.ExchangeFanout})
+		c.broker.bind(c.broker.declareQueue(DeadLetterQueue(queue)), DeadLetterExchange(queue), "")
+	}
+	q := c.broker.queues[queue]
+	c.broker.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				d, ok := c.next(ctx, q)
+				if !ok {
+					return
+				}
+				c.handle(context.WithoutCancel(ctx), queue, handler, options, d)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// next waits for a message in q, or returns false once ctx is done or the
+// client is closed.
+func (c *FakeClient) next(ctx context.Context, q *fakeQueue) (amqp.Delivery, bool) {
+	for {
+		c.broker.mu.Lock()
+		if len(q.messages) > 0 {
+			d := q.messages[0]
+			q.messages = q.messages[1:]
+			c.broker.mu.Unlock()
+			return d, true
+		}
+		ready := q.ready
+		c.broker.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return amqp.Delivery{}, false
+		case <-c.done:
+			return amqp.Delivery{}, false
+		}
+	}
+}
+
+// handle runs handler on d and settles it as the Client consumer does, with
+// retries going straight back to queue instead of waiting for their delay.
+func (c *FakeClient) handle(ctx context.Context, queue string, handler Handler, options ConsumerOptions, d amqp.Delivery) {
+	err := callHandler(ctx, handler, d)
+	attempts := Attempts(d) + 1
+	switch settlement(d, err, options) {
+	case outcomeRequeue:
+		c.broker.mu.Lock()
+		d.Redelivered = true
+		c.broker.queues[queue].push(d)
+		c.broker.mu.Unlock()
+	case outcomeRetry:
+		c.broker.publish(message{key: queue, publishing: republishing(d, failureHeaders(d, attempts, err))})
+	case outcomeDeadLetter:
+		c.broker.publish(message{exchange: DeadLetterExchange(queue), publishing: republishing(d, failureHeaders(d, attempts, err))})
+	case outcomeDrop:
+		c.config.logf("rabbitmq: dropping message %s from %s: %v", deliveryID(d), queue, err)
+	}
+}
+
+// DeadLetters returns up to limit messages from the dead letter queue of
+// queue without removing them, like Client.DeadLetters.
+func (c *FakeClient) DeadLetters(queue string, limit int) ([]DeadLetter, error) {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	var dead []DeadLetter
+	if q, ok := c.broker.queues[DeadLetterQueue(queue)]; ok {
+		for _, d := range q.messages[:min(limit, len(q.messages))] {
+			dead = append(dead, newDeadLetter(d))
+		}
+	}
+	return dead, nil
+}
+
+// ReplayDeadLetters moves up to limit messages from the dead letter queue of
+// queue back to queue, like Client.ReplayDeadLetters.
+func (c *FakeClient) ReplayDeadLetters(ctx context.Context, queue string, limit int) (int, error) {
+	replayed := 0
+	for replayed < limit {
+		if err := ctx.Err(); err != nil {
+			return replayed, err
+		}
+		c.broker.mu.Lock()
+		q, ok := c.broker.queues[DeadLetterQueue(queue)]
+		if !ok || len(q.messages) == 0 {
+			c.broker.mu.Unlock()
+			break
+		}
+		d := q.messages[0]
+		q.messages = q.messages[1:]
+		c.broker.mu.Unlock()
+
+		if _, err := c.broker.publish(message{key: queue, publishing: replayPublishing(d)}); err != nil {
+			return replayed, fmt.Errorf("error replaying dead letter: %w", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// Call publishes body to queue and waits for the reply of its Serve handler,
+// like Client.Call. It fails with ErrNoResponder when queue does not exist.
+func (c *FakeClient) Call(ctx context.Context, queue string, headers amqp.Table, body []byte) (amqp.Delivery, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultCallTimeout)
+		defer cancel()
+	}
+	msg := newMessage(c.name, c.config, "", queue, headers, body)
+	msg.publishing.ContentType = ""
+	msg.publishing.CorrelationId = fmt.Sprintf("%s-%d", c.name, callCount.Add(1))
+	msg.publishing.ReplyTo = directReplyTo + "." + msg.publishing.CorrelationId
+
+	reply := make(chan rpcReply, 1)
+	c.broker.mu.Lock()
+	_, ok := c.broker.queues[queue]
+	if ok {
+		c.broker.calls[msg.publishing.ReplyTo] = reply
+	}
+	c.broker.mu.Unlock()
+	if !ok {
+		return amqp.Delivery{}, ErrNoResponder
+	}
+	defer func() {
+		c.broker.mu.Lock()
+		delete(c.broker.calls, msg.publishing.ReplyTo)
+		c.broker.mu.Unlock()
+	}()
+	if err := c.publish(ctx, msg); err != nil {
+		return amqp.Delivery{}, err
+	}
+	return awaitReply(ctx, queue, reply)
+}
+
+// Serve answers the calls to queue with handler, like Client.Serve.
+func (c *FakeClient) Serve(ctx context.Context, queue string, handler RPCHandler, options ConsumerOptions) error {
+	if queue == "" {
+		return errors.New("rpc requires a named queue")
+	}
+	return c.Consume(ctx, "", queue, replyHandler(c.name, handler, c.publish), options)
+}
+
+// State returns StateConnected until Close is called.
+func (c *FakeClient) State() State {
+	return State(c.state.Load())
+}
+
+// OnStateChange registers fn to be called when the client is closed.
+func (c *FakeClient) OnStateChange(fn func(State)) {
+	c.mu.Lock()
+	c.listeners = append(c.listeners, fn)
+	c.mu.Unlock()
+}
+
+// CheckHealth reports the client state.
+func (c *FakeClient) CheckHealth(ctx context.Context) health.Check {
+	state := c.State()
+	details := map[string]any{"client": c.name, "state": state.String()}
+	if state != StateConnected {
+		return health.Check{Status: health.StatusDown, Details: details, Error: "connection " + state.String()}
+	}
+	return health.Check{Status: health.StatusUp, Details: details}
+}
+
+// Close stops the consumers of the client and makes Send fail.
+func (c *FakeClient) Close() error {
+	c.closeOnce.Do(func() {
+		c.state.Store(int32(StateClosed))
+		close(c.done)
+		c.mu.Lock()
+		listeners := c.listeners
+		c.mu.Unlock()
+		for _, fn := range listeners {
+			fn(StateClosed)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,245 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	defaultPublishChannels = 4
+	defaultBlockedTimeout  = 5 * time.Second
+	defaultDrainTimeout    = 5 * time.Second
+	drainPollInterval      = 50 * time.Millisecond
+)
+
+// ErrBlocked is returned by Send when the broker is throttling publishers
+// (connection blocked by a resource alarm, or channel flow stopped) for
+// longer than Config.BlockedTimeout.
+var ErrBlocked = errors.New("rabbitmq publishing blocked by broker")
+
+// channelPool lends publishing channels to one goroutine at a time. slots
+// bounds how many publishes run at once; channels are opened lazily on the
+// current connection and dropped once closed, so a reconnection renews them.
+type channelPool struct {
+	slots chan struct{}
+
+	mu      sync.Mutex
+	idle    []*amqp.Channel
+	closing bool
+	blocked bool
+	stopped int
+	ready   chan struct{}
+}
+
+func newChannelPool(size int) channelPool {
+	if size <= 0 {
+		size = defaultPublishChannels
+	}
+	ready := make(chan struct{})
+	close(ready)
+	return channelPool{slots: make(chan struct{}, size), ready: ready}
+}
+
+func (p *channelPool) paused() bool {
+	return p.blocked || p.stopped > 0
+}
+
+// throttle applies update to the flow control state, opening or closing the
+// ready gate publishers wait on. p.mu must not be held.
+func (p *channelPool) throttle(update func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	wasPaused := p.paused()
+	update()
+	switch {
+	case !wasPaused && p.paused():
+		p.ready = make(chan struct{})
+	case wasPaused && !p.paused():
+		close(p.ready)
+	}
+}
+
+// Blocked reports whether the broker is currently throttling publishers.
+func (r *Client) Blocked() bool {
+	if r == nil {
+		return false
+	}
+	r.pool.mu.Lock()
+	defer r.pool.mu.Unlock()
+	return r.pool.paused()
+}
+
+// awaitReady waits until the broker accepts publishes, failing with
+// ErrBlocked after Config.BlockedTimeout and with ErrNotConnected once the
+// client is shutting down.
+func (r *Client) awaitReady(ctx context.Context) error {
+	r.pool.mu.Lock()
+	closing, ready := r.pool.closing, r.pool.ready
+	r.pool.mu.Unlock()
+	if closing {
+		return ErrNotConnected
+	}
+
+	timeout := r.config.BlockedTimeout
+	if timeout <= 0 {
+		timeout = defaultBlockedTimeout
+	}
+	blocked := time.NewTimer(timeout)
+	defer blocked.Stop()
+	select {
+	case <-ready:
+		return nil
+	case <-blocked.C:
+		return ErrBlocked
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.done:
+		return ErrNotConnected
+	}
+}
+
+// checkout waits until the broker accepts publishes and a slot is free, and
+// returns a channel for the caller's exclusive use until checkin.
+func (r *Client) checkout(ctx context.Context) (*amqp.Channel, error) {
+	if err := r.awaitReady(ctx); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r.pool.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-r.done:
+		return nil, ErrNotConnected
+	}
+
+	r.pool.mu.Lock()
+	if r.pool.closing {
+		r.pool.mu.Unlock()
+		<-r.pool.slots
+		return nil, ErrNotConnected
+	}
+	for len(r.pool.idle) > 0 {
+		ch := r.pool.idle[len(r.pool.idle)-1]
+		r.pool.idle = r.pool.idle[:len(r.pool.idle)-1]
+		if !ch.IsClosed() {
+			r.pool.mu.Unlock()
+			return ch, nil
+		}
+	}
+	r.pool.mu.Unlock()
+
+	ch, err := r.openPublishChannel()
+	if err != nil {
+		<-r.pool.slots
+		return nil, err
+	}
+	return ch, nil
+}
+
+// checkin returns ch to the pool, unless it was closed by an error, and frees
+// its slot.
+func (r *Client) checkin(ch *amqp.Channel) {
+	r.pool.mu.Lock()
+	if !ch.IsClosed() && !r.pool.closing {
+		r.pool.idle = append(r.pool.idle, ch)
+	}
+	r.pool.mu.Unlock()
+	<-r.pool.slots
+}
+
+// openPublishChannel opens a channel on the current connection with the
+// publishing guarantees of the config.
+func (r *Client) openPublishChannel() (*amqp.Channel, error) {
+	r.mu.RLock()
+	conn := r.conn
+	r.mu.RUnlock()
+	if conn == nil || conn.IsClosed() || r.State() != StateConnected {
+		return nil, ErrNotConnected
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("error getting rabbit channel: %w", err)
+	}
+	if r.config.Confirm {
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			return nil, fmt.Errorf("error enabling publisher confirms: %w", err)
+		}
+	}
+	if r.config.Mandatory {
+		go r.handleReturns(ch.NotifyReturn(make(chan amqp.Return, 16)))
+	}
+	go r.watchFlow(ch.NotifyFlow(make(chan bool, 1)))
+	return ch, nil
+}
+
+// watchFlow pauses publishing while the broker has stopped the flow of one
+// channel, until it resumes or the channel closes.
+func (r *Client) watchFlow(flow <-chan bool) {
+	stopped := false
+	for active := range flow {
+		if active == stopped {
+			stopped = !active
+			r.pool.throttle(func() {
+				if stopped {
+					r.pool.stopped++
+				} else {
+					r.pool.stopped--
+				}
+			})
+		}
+	}
+	if stopped {
+		r.pool.throttle(func() { r.pool.stopped-- })
+	}
+}
+
+// watchBlocked pauses publishing while the broker blocks conn, e.g. on a
+// memory or disk alarm, until it unblocks it or the connection closes.
+func (r *Client) watchBlocked(blockings <-chan amqp.Blocking) {
+	for b := range blockings {
+		r.pool.throttle(func() { r.pool.blocked = b.Active })
+	}
+	r.pool.throttle(func() { r.pool.blocked = false })
+}
+
+// drain stops new publishes and waits until the outbox is empty and the
+// publishes in flight have finished, or ctx is done.
+func (r *Client) drain(ctx context.Context) error {
+	var err error
+	if r.config.OutboxSize > 0 {
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+	flush:
+		for r.Pending() > 0 && r.State() == StateConnected {
+			r.outbox.notify()
+			select {
+			case <-ctx.Done():
+				break flush
+			case <-ticker.C:
+			}
+		}
+		if n := r.Pending(); n > 0 {
+			err = fmt.Errorf("%d messages left in the outbox", n)
+		}
+	}
+
+	r.pool.mu.Lock()
+	r.pool.closing = true
+	r.pool.idle = nil
+	r.pool.mu.Unlock()
+	for i := 0; i < cap(r.pool.slots); i++ {
+		select {
+		case r.pool.slots <- struct{}{}:
+		case <-ctx.Done():
+			return errors.Join(err, fmt.Errorf("waiting for publishes in flight: %w", ctx.Err()))
+		}
+	}
+	return err
+}
@@ -0,0 +1,307 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	defaultConfirmTimeout    = 5 * time.Second
+	outboxRetryInterval      = time.Second
+	defaultOutboxMaxAttempts = 5
+)
+
+var (
+	// ErrNacked is returned by Send when the broker rejects a message in
+	// confirm mode.
+	ErrNacked = errors.New("rabbitmq message nacked by broker")
+	// ErrConfirmTimeout is returned by Send when the broker does not confirm a
+	// message within Config.ConfirmTimeout. The message may still have been
+	// delivered.
+	ErrConfirmTimeout = errors.New("rabbitmq message confirm timed out")
+	// ErrOutboxFull is returned by Send when a message could not be published
+	// and the outbox has no room left to retry it later.
+	ErrOutboxFull = errors.New("rabbitmq outbox full")
+)
+
+// message is a publishing waiting in the outbox.
+type message struct {
+	exchange   string
+	key        string
+	publishing amqp.Publishing
+	attempts   int
+}
+
+// DroppedMessage is a message dropped from the outbox after
+// Config.OutboxMaxAttempts failed attempts, with the last error.
+type DroppedMessage struct {
+	Exchange   string
+	RoutingKey string
+	Publishing amqp.Publishing
+	Err        error
+}
+
+// outbox buffers the messages that could not be published, in order. turn
+// is held while publishing, by Send or by the flusher, so a Send cannot
+// overtake the messages waiting in the outbox.
+type outbox struct {
+	mu       sync.Mutex
+	messages []message
+	wake     chan struct{}
+	turn     chan struct{}
+}
+
+func (o *outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// withSource copies headers adding the client name as the "source" header,
+// leaving the caller's table untouched.
+func withSource(headers amqp.Table, source string) amqp.Table {
+	table := make(amqp.Table, len(headers)+1)
+	for k, v := range headers {
+		table[k] = v
+	}
+	table["source"] = source
+	return table
+}
+
+// newMessage builds the publishing Send makes for the client source.
+func newMessage(source string, config Config, exchange, key string, headers amqp.Table, body []byte) message {
+	msg := message{
+		exchange: exchange,
+		key:      key,
+		publishing: amqp.Publishing{
+			Headers:     withSource(headers, source),
+			ContentType: "text/plain",
+			Timestamp:   time.Now(),
+			Body:        body,
+		},
+	}
+	if config.Persistent {
+		msg.publishing.DeliveryMode = amqp.Persistent
+	}
+	return msg
+}
+
+// Send publishes body to exchange, adding the client name as the "source"
+// header. A nil client (remote publishing disabled) discards the message.
+func (r *Client) Send(exchange string, headers amqp.Table, body []byte) error {
+	return r.SendContext(context.Background(), exchange, headers, body)
+}
+
+// SendContext is Send bound to ctx, which limits the wait for the broker
+// confirmation in confirm mode.
+func (r *Client) SendContext(ctx context.Context, exchange string, headers amqp.Table, body []byte) error {
+	return r.SendWithKeyContext(ctx, exchange, "", headers, body)
+}
+
+// SendWithKey is Send with a routing key, such as notification.NewTask or
+// logs.error, so consumers of topic and direct exchanges can bind only to
+// the messages they handle. Fanout exchanges ignore the key.
+func (r *Client) SendWithKey(exchange, key string, headers amqp.Table, body []byte) error {
+	return r.SendWithKeyContext(context.Background(), exchange, key, headers, body)
+}
+
+// SendWithKeyContext is SendWithKey bound to ctx.
+//
+// Without an outbox Send fails with ErrNotConnected while reconnecting, and in
+// confirm mode with ErrNacked or ErrConfirmTimeout. With an outbox those
+// messages are buffered and retried in the background, and Send only fails
+// with ErrOutboxFull (or ctx's error), so delivery is at least once unless a
+// message is dropped after Config.OutboxMaxAttempts (see OnDrop).
+func (r *Client) SendWithKeyContext(ctx context.Context, exchange, key string, headers amqp.Table, body []byte) error {
+	if r == nil {
+		return nil
+	}
+	msg := newMessage(r.name, r.config, exchange, key, headers, body)
+	if r.config.OutboxSize <= 0 {
+		return r.publish(ctx, msg)
+	}
+
+	// Keep the order: publish directly only when nothing is waiting, holding
+	// the turn so the outbox is not flushed meanwhile.
+	select {
+	case r.outbox.turn <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-r.outbox.turn }()
+	if r.Pending() == 0 {
+		err := r.publish(ctx, msg)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if r.rejected(err) {
+			msg.attempts++
+		}
+	}
+	return r.enqueue(msg)
+}
+
+// rejected reports whether the failed publish counts as an attempt of the
+// message, i.e. the broker was reachable and did not throttle publishers.
+func (r *Client) rejected(err error) bool {
+	return r.State() == StateConnected && !errors.Is(err, ErrNotConnected) && !errors.Is(err, ErrBlocked)
+}
+
+// publish sends msg on a pooled channel and, in confirm mode, waits for the
+// broker confirmation before returning the channel.
+func (r *Client) publish(ctx context.Context, msg message) error {
+	ch, err := r.checkout(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.checkin(ch)
+
+	if !r.config.Confirm {
+		return ch.PublishWithContext(ctx, msg.exchange, msg.key, r.config.Mandatory, false, msg.publishing)
+	}
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, msg.exchange, msg.key, r.config.Mandatory, false, msg.publishing)
+	if err != nil {
+		return err
+	}
+	timeout := r.config.ConfirmTimeout
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	acked, err := confirm.WaitContext(waitCtx)
+	switch {
+	case err != nil && ctx.Err() == nil:
+		return ErrConfirmTimeout
+	case err != nil:
+		return err
+	case !acked:
+		return ErrNacked
+	}
+	return nil
+}
+
+func (r *Client) enqueue(msg message) error {
+	r.outbox.mu.Lock()
+	defer r.outbox.mu.Unlock()
+	if len(r.outbox.messages) >= r.config.OutboxSize {
+		return ErrOutboxFull
+	}
+	r.outbox.messages = append(r.outbox.messages, msg)
+	r.outbox.notify()
+	return nil
+}
+
+// Pending returns how many messages wait in the outbox.
+func (r *Client) Pending() int {
+	if r == nil {
+		return 0
+	}
+	r.outbox.mu.Lock()
+	defer r.outbox.mu.Unlock()
+	return len(r.outbox.messages)
+}
+
+// flushOutbox retries the outbox, in order, whenever the client reconnects, a
+// message is enqueued or outboxRetryInterval passes, until Close.
+func (r *Client) flushOutbox() {
+	ticker := time.NewTicker(outboxRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.outbox.wake:
+		case <-ticker.C:
+		}
+
+		for r.flushNext() {
+		}
+	}
+}
+
+// flushNext publishes the first message of the outbox and reports whether
+// the next one should be tried at once. The message is removed once
+// published, or dropped after Config.OutboxMaxAttempts attempts.
+func (r *Client) flushNext() bool {
+	select {
+	case r.outbox.turn <- struct{}{}:
+	case <-r.done:
+		return false
+	}
+	defer func() { <-r.outbox.turn }()
+
+	r.outbox.mu.Lock()
+	if len(r.outbox.messages) == 0 {
+		r.outbox.mu.Unlock()
+		return false
+	}
+	msg := r.outbox.messages[0]
+	r.outbox.mu.Unlock()
+
+	err := r.publish(context.Background(), msg)
+	if err != nil && !r.rejected(err) {
+		return false
+	}
+	maxAttempts := r.config.OutboxMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultOutboxMaxAttempts
+	}
+	msg.attempts++
+	r.outbox.mu.Lock()
+	if err == nil || msg.attempts >= maxAttempts {
+		r.outbox.messages = r.outbox.messages[1:]
+	} else {
+		r.outbox.messages[0].attempts = msg.attempts
+	}
+	r.outbox.mu.Unlock()
+
+	if err == nil {
+		return true
+	}
+	if msg.attempts < maxAttempts {
+		return false
+	}
+	r.mu.RLock()
+	handlers := r.drops
+	r.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(DroppedMessage{Exchange: msg.exchange, RoutingKey: msg.key, Publishing: msg.publishing, Err: err})
+	}
+	return true
+}
+
+// OnDrop registers fn to receive the messages dropped from the outbox after
+// Config.OutboxMaxAttempts failed attempts, e.g. to log them or store them
+// elsewhere.
+func (r *Client) OnDrop(fn func(DroppedMessage)) {
+	r.mu.Lock()
+	r.drops = append(r.drops, fn)
+	r.mu.Unlock()
+}
+
+// OnReturn registers fn to receive the messages the broker returns because
+// they were published with Config.Mandatory and no queue could take them.
+func (r *Client) OnReturn(fn func(amqp.Return)) {
+	r.mu.Lock()
+	r.returns = append(r.returns, fn)
+	r.mu.Unlock()
+}
+
+// handleReturns dispatches the returns of one channel until it is closed.
+func (r *Client) handleReturns(returns <-chan amqp.Return) {
+	for ret := range returns {
+		r.mu.RLock()
+		handlers := r.returns
+		r.mu.RUnlock()
+		for _, fn := range handlers {
+			fn(ret)
+		}
+	}
+}
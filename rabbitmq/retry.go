@@ -0,0 +1,281 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers added to retried and dead lettered messages, next to "source".
+const (
+	AttemptsHeader           = "attempts"
+	LastErrorHeader          = "last_error"
+	OriginalExchangeHeader   = "original_exchange"
+	OriginalRoutingKeyHeader = "original_routing_key"
+)
+
+// RetryPolicy retries failed deliveries with increasing delays. Each delay
+// is a queue whose messages expire after that TTL and flow back into the
+// consumer queue, so waiting messages do not hold a worker or block others.
+//
+// Fields:
+//   - Delays: The delay before each retry, e.g. 5s, 30s, 5m. Retries beyond
+//     the last tier keep using the last delay.
+//   - MaxAttempts: Total deliveries, the first one included, before the
+//     message is dead lettered. Defaults to len(Delays) + 1.
+type RetryPolicy struct {
+	Delays      []time.Duration
+	MaxAttempts int
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return len(p.Delays) + 1
+}
+
+// delay returns the delay before the given retry (1 for the first one).
+func (p RetryPolicy) delay(retry int) time.Duration {
+	if len(p.Delays) == 0 {
+		return time.Second
+	}
+	if retry > len(p.Delays) {
+		retry = len(p.Delays)
+	}
+	return p.Delays[retry-1]
+}
+
+// DeadLetterExchange returns the name of the exchange dead lettering the
+// messages of queue.
+func DeadLetterExchange(queue string) string {
+	return queue + ".dlx"
+}
+
+// DeadLetterQueue returns the name of the queue holding the dead lettered
+// messages of queue.
+func DeadLetterQueue(queue string) string {
+	return queue + ".dead"
+}
+
+// RetryQueue returns the name of the queue holding the messages of queue
+// waiting delay before being retried.
+func RetryQueue(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, delay)
+}
+
+// Attempts returns how many times d was already delivered and failed, taken
+// from its attempts header.
+func Attempts(d amqp.Delivery) int {
+	switch n := d.Headers[AttemptsHeader].(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	}
+	return 0
+}
+
+// declareRetryTopology declares the dead letter exchange and queue of queue
+// and one retry queue per delay tier. It returns the arguments queue must be
+// declared with so the broker dead letters its rejected messages too.
+func declareRetryTopology(ch *amqp.Channel, queue string, options ConsumerOptions) (amqp.Table, error) {
+	dlx, dlq := DeadLetterExchange(queue), DeadLetterQueue(queue)
+	if err := ch.ExchangeDeclare(dlx, "fanout", options.Durable, false, false, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to declare dead letter exchange: %w", err)
+	}
+	if _, err := ch.QueueDeclare(dlq, options.Durable, false, false, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to declare dead letter queue: %w", err)
+	}
+	if err := ch.QueueBind(dlq, "", dlx, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to bind dead letter queue: %w", err)
+	}
+
+	if options.Retry != nil {
+		delays := options.Retry.Delays
+		if len(delays) == 0 {
+			delays = []time.Duration{options.Retry.delay(1)}
+		}
+		for _, delay := range delays {
+			_, err := ch.QueueDeclare(RetryQueue(queue, delay), options.Durable, false, false, false, amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to declare retry queue: %w", err)
+			}
+		}
+	}
+	return amqp.Table{"x-dead-letter-exchange": dlx}, nil
+}
+
+// republishing copies d into a new publishing with headers.
+func republishing(d amqp.Delivery, headers amqp.Table) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
+
+// failureHeaders copies the headers of d adding the attempt count, the error
+// and where the message was originally published.
+func failureHeaders(d amqp.Delivery, attempts int, err error) amqp.Table {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	// The attempts header replaces the broker's own x-death bookkeeping.
+	delete(headers, "x-death")
+	headers[AttemptsHeader] = int32(attempts)
+	headers[LastErrorHeader] = err.Error()
+	if _, ok := headers[OriginalExchangeHeader]; !ok {
+		headers[OriginalExchangeHeader] = d.Exchange
+		headers[OriginalRoutingKeyHeader] = d.RoutingKey
+	}
+	return headers
+}
+
+// retry publishes a copy of d to the retry queue of its attempt.
+func (c consumer) retry(ctx context.Context, d amqp.Delivery, attempts int, err error) error {
+	queue := RetryQueue(c.queue, c.options.Retry.delay(attempts))
+	return publishConfirmed(ctx, c.ch, "", queue, republishing(d, failureHeaders(d, attempts, err)))
+}
+
+// deadLetter publishes a copy of d to the dead letter exchange of the queue.
+func (c consumer) deadLetter(ctx context.Context, d amqp.Delivery, err error) error {
+	msg := republishing(d, failureHeaders(d, Attempts(d)+1, err))
+	return publishConfirmed(ctx, c.ch, DeadLetterExchange(c.queue), "", msg)
+}
+
+// publishConfirmed publishes msg on ch, which must be in confirm mode, and
+// waits for the broker ack.
+func publishConfirmed(ctx context.Context, ch *amqp.Channel, exchange, key string, msg amqp.Publishing) error {
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, defaultConfirmTimeout)
+	defer cancel()
+	acked, err := confirm.WaitContext(waitCtx)
+	if err != nil {
+		return ErrConfirmTimeout
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+// DeadLetter is a message found in a dead letter queue.
+type DeadLetter struct {
+	Delivery           amqp.Delivery
+	Attempts           int
+	LastError          string
+	OriginalExchange   string
+	OriginalRoutingKey string
+}
+
+func newDeadLetter(d amqp.Delivery) DeadLetter {
+	dead := DeadLetter{Delivery: d, Attempts: Attempts(d)}
+	dead.LastError, _ = d.Headers[LastErrorHeader].(string)
+	dead.OriginalExchange, _ = d.Headers[OriginalExchangeHeader].(string)
+	dead.OriginalRoutingKey, _ = d.Headers[OriginalRoutingKeyHeader].(string)
+	if dead.OriginalExchange == "" {
+		// Dead lettered by the broker: it records the origin in x-death.
+		if deaths, ok := d.Headers["x-death"].([]any); ok && len(deaths) > 0 {
+			if death, ok := deaths[0].(amqp.Table); ok {
+				dead.OriginalExchange, _ = death["exchange"].(string)
+				if keys, ok := death["routing-keys"].([]any); ok && len(keys) > 0 {
+					dead.OriginalRoutingKey, _ = keys[0].(string)
+				}
+			}
+		}
+	}
+	return dead
+}
+
+// DeadLetters returns up to limit messages from the dead letter queue of
+// queue without removing them.
+func (r *Client) DeadLetters(queue string, limit int) ([]DeadLetter, error) {
+	ch, err := r.channel()
+	if err != nil {
+		return nil, err
+	}
+	// Closing the channel requeues every message fetched without ack.
+	defer ch.Close()
+
+	var dead []DeadLetter
+	for len(dead) < limit {
+		d, ok, err := ch.Get(DeadLetterQueue(queue), false)
+		if err != nil {
+			return nil, fmt.Errorf("error reading dead letters of %s: %w", queue, err)
+		}
+		if !ok {
+			break
+		}
+		dead = append(dead, newDeadLetter(d))
+	}
+	return dead, nil
+}
+
+// ReplayDeadLetters moves up to limit messages from the dead letter queue of
+// queue back to queue, with their attempt count reset. They are published
+// through the default exchange so only queue receives them again, not every
+// queue bound to the exchange they were originally published to. It returns
+// how many messages were replayed.
+func (r *Client) ReplayDeadLetters(ctx context.Context, queue string, limit int) (int, error) {
+	ch, err := r.channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+	if err := ch.Confirm(false); err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for replayed < limit {
+		d, ok, err := ch.Get(DeadLetterQueue(queue), false)
+		if err != nil {
+			return replayed, fmt.Errorf("error reading dead letters of %s: %w", queue, err)
+		}
+		if !ok {
+			break
+		}
+		if err := publishConfirmed(ctx, ch, "", queue, replayPublishing(d)); err != nil {
+			d.Nack(false, true)
+			return replayed, fmt.Errorf("error replaying dead letter: %w", err)
+		}
+		d.Ack(false)
+		replayed++
+	}
+	return replayed, nil
+}
+
+// replayPublishing copies the dead letter d into a new publishing without the
+// failure headers, so it is handled as a first attempt again.
+func replayPublishing(d amqp.Delivery) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	for _, k := range []string{AttemptsHeader, LastErrorHeader, OriginalExchangeHeader, OriginalRoutingKeyHeader, "x-death"} {
+		delete(headers, k)
+	}
+	return republishing(d, headers)
+}
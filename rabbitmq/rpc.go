@@ -0,0 +1,292 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// directReplyTo is the pseudo queue RabbitMQ routes replies through
+	// without declaring a reply queue per caller.
+	directReplyTo = "amq.rabbitmq.reply-to"
+
+	// DefaultCallTimeout bounds Call when ctx has no deadline.
+	DefaultCallTimeout = 10 * time.Second
+
+	// ErrorHeader carries the error returned by the RPCHandler in a reply.
+	ErrorHeader = "error"
+)
+
+// ErrNoResponder is returned by Call when no queue is bound to receive the
+// request, i.e. no server is listening on it.
+var ErrNoResponder = errors.New("rabbitmq rpc request unroutable")
+
+// RemoteError is returned by Call when the server handler failed.
+type RemoteError struct {
+	Queue   string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("rpc %s failed: %s", e.Queue, e.Message)
+}
+
+// RPCHandler answers a request received by Serve. The returned body is sent
+// back to the caller; a returned error is sent instead and Call fails with a
+// RemoteError carrying its message.
+type RPCHandler func(ctx context.Context, request amqp.Delivery) ([]byte, error)
+
+// rpcReply is the outcome of a call, delivered by the reply dispatcher.
+type rpcReply struct {
+	delivery amqp.Delivery
+	err      error
+}
+
+// rpc holds the channel consuming direct replies and the calls waiting on it.
+// Direct reply-to requires requests to be published on that same channel.
+type rpc struct {
+	mu      sync.Mutex
+	ch      *amqp.Channel
+	pending map[string]chan rpcReply
+}
+
+var callCount atomic.Uint64
+
+// Call publishes body to queue through the default exchange and waits for the
+// reply of the server listening on it (see Serve), matched by correlation ID.
+// It fails with ctx's error when ctx is done first, with DefaultCallTimeout
+// applied if ctx has no deadline; the request expires in the queue at the same
+// time so a late server does not handle it. It fails with ErrNoResponder when
+// the queue does not exist and with a RemoteError when the handler failed.
+//
+// Requests are published on the reply channel rather than through the
+// publishing pool, since direct reply-to requires it, but they wait on the same
+// flow control as Send and fail with ErrBlocked, or with ErrNotConnected once
+// Shutdown started. Shutdown does not wait for calls in flight: they fail with
+// ErrNotConnected when the connection closes.
+func (r *Client) Call(ctx context.Context, queue string, headers amqp.Table, body []byte) (amqp.Delivery, error) {
+	if r == nil {
+		return amqp.Delivery{}, ErrNotConnected
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultCallTimeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+
+	id := fmt.Sprintf("%s-%d", r.name, callCount.Add(1))
+	publishing := amqp.Publishing{
+		Headers:       withSource(headers, r.name),
+		CorrelationId: id,
+		ReplyTo:       directReplyTo,
+		Expiration:    strconv.FormatInt(max(time.Until(deadline).Milliseconds(), 1), 10),
+		Timestamp:     time.Now(),
+		Body:          body,
+	}
+
+	if err := r.awaitReady(ctx); err != nil {
+		return amqp.Delivery{}, err
+	}
+	// The call is registered before publishing so a fast reply finds it, but
+	// the lock is not held while publishing so other calls and the reply
+	// dispatcher do not wait on the broker.
+	reply := make(chan rpcReply, 1)
+	r.rpc.mu.Lock()
+	ch, err := r.replyChannel()
+	if err == nil {
+		r.rpc.pending[id] = reply
+	}
+	r.rpc.mu.Unlock()
+	if err != nil {
+		return amqp.Delivery{}, err
+	}
+	defer r.forgetCall(id)
+	if err := ch.PublishWithContext(ctx, "", queue, true, false, publishing); err != nil {
+		return amqp.Delivery{}, err
+	}
+
+	return awaitReply(ctx, queue, reply)
+}
+
+// awaitReply waits for the reply of a call to queue, or for ctx to be done.
+func awaitReply(ctx context.Context, queue string, reply <-chan rpcReply) (amqp.Delivery, error) {
+	select {
+	case <-ctx.Done():
+		return amqp.Delivery{}, fmt.Errorf("rpc %s: %w", queue, ctx.Err())
+	case res := <-reply:
+		if res.err != nil {
+			return amqp.Delivery{}, res.err
+		}
+		if msg, failed := res.delivery.Headers[ErrorHeader].(string); failed {
+			return res.delivery, &RemoteError{Queue: queue, Message: msg}
+		}
+		return res.delivery, nil
+	}
+}
+
+// replyChannel returns the channel consuming direct replies, opening it if
+// needed. r.rpc.mu must be held.
+func (r *Client) replyChannel() (*amqp.Channel, error) {
+	if r.rpc.ch != nil && !r.rpc.ch.IsClosed() {
+		return r.rpc.ch, nil
+	}
+	ch, err := r.channel()
+	if err != nil {
+		return nil, err
+	}
+	// Direct replies must be consumed in no-ack mode.
+	replies, err := ch.Consume(directReplyTo, "", true, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("error consuming rpc replies: %w", err)
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 16))
+	r.rpc.ch = ch
+	if r.rpc.pending == nil {
+		r.rpc.pending = map[string]chan rpcReply{}
+	}
+	go r.dispatchReplies(ch, replies, returns)
+	return ch, nil
+}
+
+// dispatchReplies hands each reply, or returned request, to the call waiting
+// for it. When the channel closes, the calls still waiting fail with
+// ErrNotConnected and the next Call opens a new channel.
+func (r *Client) dispatchReplies(ch *amqp.Channel, replies <-chan amqp.Delivery, returns <-chan amqp.Return) {
+	for replies != nil || returns != nil {
+		select {
+		case d, ok := <-replies:
+			if !ok {
+				replies = nil
+				continue
+			}
+			r.resolveCall(d.CorrelationId, rpcReply{delivery: d})
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			r.resolveCall(ret.CorrelationId, rpcReply{err: ErrNoResponder})
+		}
+	}
+
+	r.rpc.mu.Lock()
+	defer r.rpc.mu.Unlock()
+	if r.rpc.ch != ch {
+		return
+	}
+	r.rpc.ch = nil
+	for id, reply := range r.rpc.pending {
+		reply <- rpcReply{err: ErrNotConnected}
+		delete(r.rpc.pending, id)
+	}
+}
+
+func (r *Client) resolveCall(id string, res rpcReply) {
+	r.rpc.mu.Lock()
+	reply, ok := r.rpc.pending[id]
+	delete(r.rpc.pending, id)
+	r.rpc.mu.Unlock()
+	if ok {
+		reply <- res
+	}
+}
+
+func (r *Client) forgetCall(id string) {
+	r.rpc.mu.Lock()
+	delete(r.rpc.pending, id)
+	r.rpc.mu.Unlock()
+}
+
+// Serve declares queue and answers the requests published to it by Call with
+// handler, until ctx is done. It is Consume on the default exchange, so
+// options set the workers, prefetch and queue flags, and it survives
+// reconnections the same way. Requests without a reply address are handled
+// as plain deliveries.
+//
+// Example:
+//
+//	go client.Serve(ctx, "users.display_name", rabbitmq.HandleJSON(func(ctx context.Context, id string) (string, error) {
+//	    return users.DisplayName(ctx, id)
+//	}), rabbitmq.ConsumerOptions{Workers: 4})
+func (r *Client) Serve(ctx context.Context, queue string, handler RPCHandler, options ConsumerOptions) error {
+	if queue == "" {
+		return errors.New("rpc requires a named queue")
+	}
+	return r.Consume(ctx, "", queue, replyHandler(r.name, handler, r.publish), options)
+}
+
+// replyHandler adapts handler into a Handler publishing its result to the
+// reply address of each request with publish.
+func replyHandler(source string, handler RPCHandler, publish func(context.Context, message) error) Handler {
+	return func(ctx context.Context, d amqp.Delivery) error {
+		body, err := handler(ctx, d)
+		if d.ReplyTo == "" {
+			return err
+		}
+		reply := amqp.Publishing{
+			Headers:       amqp.Table{"source": source},
+			ContentType:   d.ContentType,
+			CorrelationId: d.CorrelationId,
+			Timestamp:     time.Now(),
+			Body:          body,
+		}
+		if err != nil {
+			reply.Headers[ErrorHeader] = err.Error()
+			reply.Body = nil
+		}
+		// A failed reply is requeued so the request is answered once the
+		// client reconnects, if the caller is still waiting.
+		return publish(ctx, message{key: d.ReplyTo, publishing: reply})
+	}
+}
+
+// CallJSON is Call with req and the reply encoded as JSON.
+//
+// Example:
+//
+//	name, err := rabbitmq.CallJSON[string, string](ctx, client, "users.display_name", userID)
+func CallJSON[Req, Res any](ctx context.Context, client ClientI, queue string, req Req) (Res, error) {
+	var res Res
+	if client == nil {
+		return res, ErrNotConnected
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return res, fmt.Errorf("error encoding rpc request: %w", err)
+	}
+	d, err := client.Call(ctx, queue, nil, body)
+	if err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(d.Body, &res); err != nil {
+		return res, fmt.Errorf("error decoding rpc reply: %w", err)
+	}
+	return res, nil
+}
+
+// HandleJSON adapts fn into an RPCHandler decoding the request and encoding
+// the reply as JSON. Requests that cannot be decoded are answered with an
+// error.
+func HandleJSON[Req, Res any](fn func(ctx context.Context, req Req) (Res, error)) RPCHandler {
+	return func(ctx context.Context, d amqp.Delivery) ([]byte, error) {
+		var req Req
+		if err := json.Unmarshal(d.Body, &req); err != nil {
+			return nil, fmt.Errorf("invalid request: %v", err)
+		}
+		res, err := fn(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(res)
+	}
+}
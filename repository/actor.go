@@ -0,0 +1,30 @@
+package repository
+
+import "context"
+
+// ActorKey is the gin context key holding the id of the authenticated user.
+// It is set by middleware.RequireRole and middleware.SetJWTDataFromToken. Pass
+// utils.RequestContext(c) to Repository methods: it carries this key along
+// with the cancellation of c.Request.Context(). Passing c itself only cancels
+// queries on engines with ContextWithFallback set, as utils.GetRouter does.
+const ActorKey = "user_id"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying userID as the acting user, for
+// callers that do not run inside a gin request (workers, consumers...).
+func WithActor(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFromContext returns the acting user stored by WithActor or by the JWT
+// middlewares, or "" when there is none.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok {
+		return actor
+	}
+	if actor, ok := ctx.Value(ActorKey).(string); ok {
+		return actor
+	}
+	return ""
+}
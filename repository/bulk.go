@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// bulkItems returns the elements of data, which must be a slice or array
+// (e.g. []Course or []any).
+func bulkItems(data any) ([]any, error) {
+	if items, ok := data.([]any); ok {
+		return items, nil
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("bulk operations require a slice, got %T", data)
+	}
+	items := make([]any, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// bulkResult is the SuccessDetails Data of the bulk operations.
+func bulkResult(created, updated int64) string {
+	return fmt.Sprintf(`{"created":%d,"updated":%d}`, created, updated)
+}
+
+// InsertMany runs InsertManyCtx with a background context.
+func (r *Repository[P]) InsertMany(data any) models.APIResponse {
+	return r.InsertManyCtx(context.Background(), data)
+}
+
+// InsertManyCtx inserts every element of data (a slice of what InsertCtx
+// accepts) in a single COPY. The parser must implement BulkParser. Either all
+// rows are inserted or none is.
+//
+// On success Data holds the counts as {"created": n, "updated": 0}.
+func (r *Repository[P]) InsertManyCtx(ctx context.Context, data any) models.APIResponse {
+	parser, ok := any(r.parser).(BulkParser)
+	if !ok {
+		return models.NewProblemDetails(500, "Bulk Insert Not Supported", fmt.Sprintf("%T does not implement BulkParser", r.parser), "repository.InsertMany")
+	}
+	items, err := bulkItems(data)
+	if err != nil {
+		return models.NewProblemDetails(500, "Bulk Insert Failed", err.Error(), "repository.InsertMany")
+	}
+	if len(items) == 0 {
+		return models.NewSuccessDetails(201, "Created", "Nothing to insert", "repository.InsertMany", bulkResult(0, 0))
+	}
+
+	// Rows are built before COPY starts so an element of the wrong type is
+	// reported as a bad request instead of failing inside pgx.
+	actor := ActorFromContext(ctx)
+	rows := make([][]any, len(items))
+	for i, item := range items {
+		if rows[i], err = parser.CopyRow(item, actor); err != nil {
+			return models.NewProblemDetails(400, "Bulk Insert Failed", fmt.Sprintf("element %d: %v", i, err), "repository.InsertMany")
+		}
+	}
+	created, err := r.db.CopyFrom(ctx, parser.CopyTable(), parser.CopyColumns(), pgx.CopyFromRows(rows))
+	if err != nil {
+		return MapError(ctx, err, "Bulk Insert Failed", "repository.InsertMany")
+	}
+	return models.NewSuccessDetails(201, "Created", "Bulk insert successful", "repository.InsertMany", bulkResult(created, 0))
+}
+
+// Upsert runs UpsertCtx with a background context.
+func (r *Repository[P]) Upsert(data any) models.APIResponse {
+	return r.UpsertCtx(context.Background(), data)
+}
+
+// UpsertCtx inserts data or, if a row with the same key already exists,
+// updates it. The parser must implement UpsertParser. The status is 201 when
+// the row was created and 200 when it was updated, and Data holds the counts
+// as in UpsertManyCtx.
+func (r *Repository[P]) UpsertCtx(ctx context.Context, data any) models.APIResponse {
+	parser, ok := any(r.parser).(UpsertParser)
+	if !ok {
+		return models.NewProblemDetails(500, "Upsert Not Supported", fmt.Sprintf("%T does not implement UpsertParser", r.parser), "repository.Upsert")
+	}
+	query, args := parser.UpsertQuery(data, ActorFromContext(ctx))
+	var inserted bool
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&inserted); err != nil {
+		return MapError(ctx, err, "Upsert Failed", "repository.Upsert")
+	}
+	if inserted {
+		return models.NewSuccessDetails(201, "Created", "Upsert successful", "repository.Upsert", bulkResult(1, 0))
+	}
+	return models.NewSuccessDetails(200, "Updated", "Upsert successful", "repository.Upsert", bulkResult(0, 1))
+}
+
+// UpsertMany runs UpsertManyCtx with a background context.
+func (r *Repository[P]) UpsertMany(data any) models.APIResponse {
+	return r.UpsertManyCtx(context.Background(), data)
+}
+
+// UpsertManyCtx upserts every element of data (a slice of what UpsertCtx
+// accepts). The statements are sent as a single batch, which Postgres runs in
+// one implicit transaction unless the repository is already bound to a Tx.
+//
+// On success Data holds how many rows were created and how many were updated,
+// as {"created": n, "updated": m}.
+func (r *Repository[P]) UpsertManyCtx(ctx context.Context, data any) models.APIResponse {
+	parser, ok := any(r.parser).(UpsertParser)
+	if !ok {
+		return models.NewProblemDetails(500, "Upsert Not Supported", fmt.Sprintf("%T does not implement UpsertParser", r.parser), "repository.UpsertMany")
+	}
+	items, err := bulkItems(data)
+	if err != nil {
+		return models.NewProblemDetails(500, "Upsert Failed", err.Error(), "repository.UpsertMany")
+	}
+
+	actor := ActorFromContext(ctx)
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		query, args := parser.UpsertQuery(item, actor)
+		batch.Queue(query, args...)
+	}
+
+	var created, updated int64
+	results := r.db.SendBatch(ctx, batch)
+	for range items {
+		var inserted bool
+		if err := results.QueryRow().Scan(&inserted); err != nil {
+			results.Close()
+			return MapError(ctx, err, "Upsert Failed", "repository.UpsertMany")
+		}
+		if inserted {
+			created++
+		} else {
+			updated++
+		}
+	}
+	if err := results.Close(); err != nil {
+		return MapError(ctx, err, "Upsert Failed", "repository.UpsertMany")
+	}
+	return models.NewSuccessDetails(200, "Upserted", "Bulk upsert successful", "repository.UpsertMany", bulkResult(created, updated))
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes handled by MapError.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeUniqueViolation      = "23505"
+	codeForeignKeyViolation  = "23503"
+	codeNotNullViolation     = "23502"
+	codeCheckViolation       = "23514"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	codeLockNotAvailable     = "55P03"
+	codeQueryCanceled        = "57014"
+	codeSyntaxError          = "42601"
+	classDataException       = "22"
+)
+
+// ErrorMapper overrides how a database error is turned into a ProblemDetails.
+// It returns false to let the next mapper, and finally the default mapping,
+// handle the error.
+type ErrorMapper func(err error, instance string) (models.ProblemDetails, bool)
+
+var (
+	errorMappersMu sync.RWMutex
+	errorMappers   []*ErrorMapper
+)
+
+// RegisterErrorMapper installs a service specific ErrorMapper and returns a
+// function removing it, e.g. for tests to call in t.Cleanup. Mappers run in
+// registration order before the default mapping, e.g. to turn a known
+// constraint name into a domain message:
+//
+//	repository.RegisterErrorMapper(func(err error, instance string) (models.ProblemDetails, bool) {
+//	    var pgErr *pgconn.PgError
+//	    if errors.As(err, &pgErr) && pgErr.ConstraintName == "users_email_key" {
+//	        return models.NewProblemDetails(409, "Conflict", "Email already registered", instance), true
+//	    }
+//	    return models.ProblemDetails{}, false
+//	})
+func RegisterErrorMapper(mapper ErrorMapper) (unregister func()) {
+	entry := &mapper
+	errorMappersMu.Lock()
+	defer errorMappersMu.Unlock()
+	errorMappers = append(errorMappers, entry)
+	return func() {
+		errorMappersMu.Lock()
+		defer errorMappersMu.Unlock()
+		// MapError iterates over a snapshot of the slice, so it is copied
+		// rather than modified in place.
+		remaining := make([]*ErrorMapper, 0, len(errorMappers))
+		for _, m := range errorMappers {
+			if m != entry {
+				remaining = append(remaining, m)
+			}
+		}
+		errorMappers = remaining
+	}
+}
+
+// MapError converts an error returned by pgx into a ProblemDetails. It is used
+// by every Repository method and can be used by services running their own
+// queries. title is only used for errors without a more specific mapping,
+// which become a 500.
+//
+// The mapping is:
+//   - cancelled context: 499; expired deadline or statement timeout: 504
+//   - pgx.ErrNoRows: 404
+//   - unique violation: 409, foreign key violation: 409
+//   - not-null violation, check violation, data exception, syntax error: 400
+//   - serialization failure, deadlock: 409 (the request can be retried)
+//   - lock not available: 503
+func MapError(ctx context.Context, err error, title, instance string) models.ProblemDetails {
+	errorMappersMu.RLock()
+	mappers := errorMappers
+	errorMappersMu.RUnlock()
+	for _, mapper := range mappers {
+		if problem, ok := (*mapper)(err, instance); ok {
+			return problem
+		}
+	}
+
+	if problem, ok := contextProblem(ctx, err, instance); ok {
+		return problem
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.NewProblemDetails(404, "Not Found", "Resource not found", instance)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		if isBadRequestError(err) {
+			return models.NewProblemDetails(400, "Bad Request", err.Error(), instance)
+		}
+		return models.NewProblemDetails(500, title, err.Error(), instance)
+	}
+
+	switch {
+	case pgErr.Code == codeUniqueViolation:
+		return models.NewProblemDetails(409, "Conflict", describe("Resource already exists", pgErr), instance)
+	case pgErr.Code == codeForeignKeyViolation:
+		return models.NewProblemDetails(409, "Conflict", describe("Related resource missing or still referenced", pgErr), instance)
+	case pgErr.Code == codeNotNullViolation:
+		return models.NewProblemDetails(400, "Bad Request", describe("Missing required value", pgErr), instance)
+	case pgErr.Code == codeCheckViolation:
+		return models.NewProblemDetails(400, "Bad Request", describe("Value not allowed", pgErr), instance)
+	case pgErr.Code == codeSyntaxError, strings.HasPrefix(pgErr.Code, classDataException):
+		return models.NewProblemDetails(400, "Bad Request", describe("Invalid input", pgErr), instance)
+	case pgErr.Code == codeSerializationFailure, pgErr.Code == codeDeadlockDetected:
+		return models.NewProblemDetails(409, "Conflict", describe("Concurrent modification, retry the request", pgErr), instance)
+	case pgErr.Code == codeLockNotAvailable:
+		return models.NewProblemDetails(503, "Service Unavailable", describe("Resource is locked, retry the request", pgErr), instance)
+	case pgErr.Code == codeQueryCanceled:
+		return models.GatewayTimeout(describe("Database operation timed out", pgErr), instance)
+	}
+	return models.NewProblemDetails(500, title, pgErr.Error(), instance)
+}
+
+// describe appends the constraint, column and detail reported by Postgres to msg.
+func describe(msg string, pgErr *pgconn.PgError) string {
+	var parts []string
+	if pgErr.ConstraintName != "" {
+		parts = append(parts, "constraint "+pgErr.ConstraintName)
+	}
+	if pgErr.ColumnName != "" {
+		parts = append(parts, "column "+pgErr.ColumnName)
+	}
+	if len(parts) > 0 {
+		msg = fmt.Sprintf("%s (%s)", msg, strings.Join(parts, ", "))
+	}
+	if pgErr.Detail != "" {
+		msg += ": " + pgErr.Detail
+	}
+	return msg
+}
+
+func isBadRequestError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "invalid input") ||
+		strings.Contains(msg, "violates not-null constraint") ||
+		strings.Contains(msg, "violates check constraint") ||
+		strings.Contains(msg, "syntax error")
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// Op is a comparison operator of a filter Condition.
+type Op string
+
+const (
+	OpEq      Op = "eq"
+	OpNeq     Op = "neq"
+	OpLt      Op = "lt"
+	OpLte     Op = "lte"
+	OpGt      Op = "gt"
+	OpGte     Op = "gte"
+	OpIn      Op = "in"
+	OpLike    Op = "like"
+	OpILike   Op = "ilike"
+	OpIsNull  Op = "null"
+	OpBetween Op = "between"
+)
+
+var comparisons = map[Op]string{
+	OpEq:    "=",
+	OpNeq:   "<>",
+	OpLt:    "<",
+	OpLte:   "<=",
+	OpGt:    ">",
+	OpGte:   ">=",
+	OpLike:  "LIKE",
+	OpILike: "ILIKE",
+}
+
+// Filter is a node of a filter expression: a Condition or a Group of filters
+// joined with AND or OR.
+//
+// SQL renders the node as a WHERE condition with placeholders starting at
+// $argOffset+1. Fields lists the columns the node refers to, so parsers can
+// check them before building a query.
+type Filter interface {
+	SQL(argOffset int) (string, []any, error)
+	Fields() []string
+}
+
+// Condition compares a single column.
+//
+// Fields:
+//   - Field: The column name.
+//   - Op: The operator.
+//   - Value: The operand. OpIn expects a slice, OpBetween a two element slice
+//     and OpIsNull a bool (true for IS NULL, false for IS NOT NULL).
+type Condition struct {
+	Field string
+	Op    Op
+	Value any
+}
+
+// Group joins filters with AND, or with OR when Or is set. An empty AND group
+// matches every row and an empty OR group matches none.
+type Group struct {
+	Or      bool
+	Filters []Filter
+}
+
+// Eq, Neq, Lt, Lte, Gt, Gte, In, Like, ILike, IsNull and Between build the
+// Condition for the operator of the same name.
+func Eq(field string, value any) Condition  { return Condition{field, OpEq, value} }
+func Neq(field string, value any) Condition { return Condition{field, OpNeq, value} }
+func Lt(field string, value any) Condition  { return Condition{field, OpLt, value} }
+func Lte(field string, value any) Condition { return Condition{field, OpLte, value} }
+func Gt(field string, value any) Condition  { return Condition{field, OpGt, value} }
+func Gte(field string, value any) Condition { return Condition{field, OpGte, value} }
+
+func In(field string, values ...any) Condition { return Condition{field, OpIn, values} }
+func Like(field, pattern string) Condition     { return Condition{field, OpLike, pattern} }
+func ILike(field, pattern string) Condition    { return Condition{field, OpILike, pattern} }
+func IsNull(field string, null bool) Condition { return Condition{field, OpIsNull, null} }
+func Between(field string, low, high any) Condition {
+	return Condition{field, OpBetween, []any{low, high}}
+}
+
+// And and Or group filters.
+func And(filters ...Filter) Group { return Group{Filters: filters} }
+func Or(filters ...Filter) Group  { return Group{Or: true, Filters: filters} }
+
+// Fields returns the condition's column.
+func (c Condition) Fields() []string {
+	return []string{c.Field}
+}
+
+// SQL renders the condition.
+func (c Condition) SQL(argOffset int) (string, []any, error) {
+	ident := pgx.Identifier{c.Field}.Sanitize()
+	if op, ok := comparisons[c.Op]; ok {
+		return fmt.Sprintf("%s %s $%d", ident, op, argOffset+1), []any{c.Value}, nil
+	}
+
+	switch c.Op {
+	case OpIsNull:
+		null, ok := c.Value.(bool)
+		if !ok {
+			return "", nil, fmt.Errorf("%s[%s] expects a boolean", c.Field, c.Op)
+		}
+		if null {
+			return ident + " IS NULL", nil, nil
+		}
+		return ident + " IS NOT NULL", nil, nil
+	case OpIn:
+		values, err := bulkItems(c.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s[%s] expects a list", c.Field, c.Op)
+		}
+		if len(values) == 0 {
+			return "FALSE", nil, nil
+		}
+		params := make([]string, len(values))
+		for i := range values {
+			params[i] = fmt.Sprintf("$%d", argOffset+i+1)
+		}
+		return fmt.Sprintf("%s IN (%s)", ident, strings.Join(params, ", ")), values, nil
+	case OpBetween:
+		values, err := bulkItems(c.Value)
+		if err != nil || len(values) != 2 {
+			return "", nil, fmt.Errorf("%s[%s] expects two values", c.Field, c.Op)
+		}
+		return fmt.Sprintf("%s BETWEEN $%d AND $%d", ident, argOffset+1, argOffset+2), values, nil
+	}
+	return "", nil, fmt.Errorf("unknown filter operator %s", c.Op)
+}
+
+// Fields returns the columns of every filter in the group.
+func (g Group) Fields() []string {
+	var fields []string
+	for _, f := range g.Filters {
+		fields = append(fields, f.Fields()...)
+	}
+	return fields
+}
+
+// SQL renders the group, parenthesizing each member.
+func (g Group) SQL(argOffset int) (string, []any, error) {
+	if len(g.Filters) == 0 {
+		if g.Or {
+			return "FALSE", nil, nil
+		}
+		return "TRUE", nil, nil
+	}
+	joiner := " AND "
+	if g.Or {
+		joiner = " OR "
+	}
+	parts := make([]string, len(g.Filters))
+	var args []any
+	for i, f := range g.Filters {
+		sql, fArgs, err := f.SQL(argOffset + len(args))
+		if err != nil {
+			return "", nil, err
+		}
+		parts[i] = "(" + sql + ")"
+		args = append(args, fArgs...)
+	}
+	return strings.Join(parts, joiner), args, nil
+}
+
+// reservedParams are query string keys used by ParsePageRequest.
+var reservedParams = map[string]bool{"limit": true, "offset": true, "cursor": true, "sort": true}
+
+// ParseFilterQuery builds an AND Group from query string values such as
+// ?due_date[gte]=2026-01-01&status[in]=open,late&title[ilike]=%25tp%25
+//
+// A key is field[op], or just field for eq. in takes a comma separated list,
+// between takes two comma separated values and null takes true or false.
+// Only fields listed in filterable may be used: field[op] keys naming any
+// other field are rejected, while plain keys that are not filterable (and the
+// pagination keys) are ignored so they can carry other parameters.
+func ParseFilterQuery(values url.Values, filterable []string) (Group, error) {
+	allowed := make(map[string]bool, len(filterable))
+	for _, field := range filterable {
+		allowed[field] = true
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var group Group
+	for _, key := range keys {
+		field, op := key, OpEq
+		if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+			field, op = key[:open], Op(key[open+1:len(key)-1])
+		} else if reservedParams[key] || !allowed[key] {
+			continue
+		}
+		if !allowed[field] {
+			return Group{}, fmt.Errorf("cannot filter by %s", field)
+		}
+
+		for _, raw := range values[key] {
+			condition, err := parseCondition(field, op, raw)
+			if err != nil {
+				return Group{}, err
+			}
+			group.Filters = append(group.Filters, condition)
+		}
+	}
+	return group, nil
+}
+
+func parseCondition(field string, op Op, raw string) (Condition, error) {
+	switch op {
+	case OpIn:
+		parts := strings.Split(raw, ",")
+		values := make([]any, len(parts))
+		for i, p := range parts {
+			values[i] = strings.TrimSpace(p)
+		}
+		return In(field, values...), nil
+	case OpBetween:
+		low, high, ok := strings.Cut(raw, ",")
+		if !ok {
+			return Condition{}, fmt.Errorf("%s[between] expects two comma separated values", field)
+		}
+		return Between(field, strings.TrimSpace(low), strings.TrimSpace(high)), nil
+	case OpIsNull:
+		null, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Condition{}, fmt.Errorf("%s[null] expects true or false", field)
+		}
+		return IsNull(field, null), nil
+	}
+	if _, ok := comparisons[op]; !ok {
+		return Condition{}, fmt.Errorf("unknown filter operator %s", op)
+	}
+	return Condition{Field: field, Op: op, Value: raw}, nil
+}
+
+// FilterParser is implemented by parsers that can select rows matching a
+// Filter. GetQueryFilter returns an error when the filter refers to an unknown
+// column or is malformed.
+type FilterParser interface {
+	GetQueryFilter(filter Filter) (string, []any, error)
+}
+
+// GetByFilter runs GetByFilterCtx with a background context.
+func (r *Repository[P]) GetByFilter(filter Filter) models.APIResponse {
+	return r.GetByFilterCtx(context.Background(), filter)
+}
+
+// GetByFilterCtx fetches the rows matching filter. The parser must implement
+// FilterParser; an invalid filter answers 400.
+//
+// Example:
+//
+//	filter, err := repository.ParseFilterQuery(c.Request.URL.Query(), []string{"status", "due_date"})
+//	if err != nil {
+//	    utils.HandleError(c, http.StatusBadRequest, err.Error(), c.Request.URL.Path)
+//	    return
+//	}
+//	resp := tasks.GetByFilterCtx(utils.RequestContext(c), filter)
+func (r *Repository[P]) GetByFilterCtx(ctx context.Context, filter Filter) models.APIResponse {
+	parser, ok := any(r.parser).(FilterParser)
+	if !ok {
+		return models.NewProblemDetails(500, "Filtering Not Supported", fmt.Sprintf("%T does not implement FilterParser", r.parser), "repository.GetByFilter")
+	}
+	query, args, err := parser.GetQueryFilter(filter)
+	if err != nil {
+		return models.BadRequest(err.Error(), "repository.GetByFilter")
+	}
+	rows, err := r.reader(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return MapError(ctx, err, "GetByFilter Failed", "repository.GetByFilter")
+	}
+	defer rows.Close()
+
+	results, err := r.parser.ScanRows(rows)
+	if err != nil {
+		return MapError(ctx, err, "Scan Failed", "repository.GetByFilter")
+	}
+
+	jsonStr, jsonErr := results.ToJSON()
+	if jsonErr != nil {
+		return models.NewProblemDetails(500, "Serialization Failed", jsonErr.Error(), "repository.GetByFilter")
+	}
+	return models.NewSuccessDetails(200, "Fetched", "Resources fetched successfully", "repository.GetByFilter", jsonStr)
+}
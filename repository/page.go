@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultPageLimit is used when a PageRequest does not set a limit.
+	DefaultPageLimit = 20
+	// MaxPageLimit caps the limit accepted from clients.
+	MaxPageLimit = 100
+)
+
+// OrderField is one column of an ORDER BY clause.
+type OrderField struct {
+	Field string
+	Desc  bool
+}
+
+// PageRequest describes which page of results to fetch.
+//
+// Offset pagination uses Limit and Offset. Keyset pagination uses Limit and
+// Cursor, where Cursor is the NextCursor returned with the previous page; when
+// Cursor is set Offset is ignored.
+type PageRequest struct {
+	Limit   int
+	Offset  int
+	Cursor  string
+	OrderBy []OrderField
+}
+
+// PagedQueryParser extends QueryParser with the queries needed to serve a page
+// of results.
+//
+//   - CountQuery returns a query selecting the total number of rows matching filters.
+//   - GetQueryPage returns the select for one page. Implementations should apply
+//     page.OrderBy, LIMIT page.Limit and either OFFSET page.Offset or, when
+//     page.Cursor is set, a KeysetCondition built from DecodeCursor.
+//   - NextCursor returns the cursor for the page following results, or "" when
+//     results is the last page.
+type PagedQueryParser interface {
+	QueryParser
+	CountQuery(filters map[string]any) (string, []any)
+	GetQueryPage(filters map[string]any, page PageRequest) (string, []any)
+	NextCursor(results models.Model, page PageRequest) (string, error)
+}
+
+// CursorValidator is implemented by PagedQueryParsers that can tell whether
+// page.Cursor fits the order of page, e.g. it was not issued for another sort.
+// GetPageCtx then rejects a cursor that does not fit with 400 instead of
+// serving an empty page.
+type CursorValidator interface {
+	ValidateCursor(page PageRequest) error
+}
+
+// Normalize applies DefaultPageLimit and MaxPageLimit and clamps a negative
+// offset to zero.
+func (p PageRequest) Normalize() PageRequest {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	return p
+}
+
+// ParsePageRequest builds a PageRequest from query string values such as
+// ?limit=20&offset=40&sort=-due_date,id or ?limit=20&cursor=...
+// A leading "-" in sort means descending order. Only fields listed in
+// sortable may be used for sorting. A cursor that cannot be decoded is
+// rejected, so handlers answer it with 400 like any other invalid parameter.
+func ParsePageRequest(values url.Values, sortable []string) (PageRequest, error) {
+	var page PageRequest
+	var err error
+
+	if v := values.Get("limit"); v != "" {
+		if page.Limit, err = strconv.Atoi(v); err != nil {
+			return PageRequest{}, fmt.Errorf("invalid limit: %s", v)
+		}
+	}
+	if v := values.Get("offset"); v != "" {
+		if page.Offset, err = strconv.Atoi(v); err != nil {
+			return PageRequest{}, fmt.Errorf("invalid offset: %s", v)
+		}
+	}
+	if page.Cursor = values.Get("cursor"); page.Cursor != "" {
+		if _, err := DecodeCursor(page.Cursor); err != nil {
+			return PageRequest{}, err
+		}
+	}
+
+	if v := values.Get("sort"); v != "" {
+		allowed := make(map[string]bool, len(sortable))
+		for _, field := range sortable {
+			allowed[field] = true
+		}
+		for _, raw := range strings.Split(v, ",") {
+			field := OrderField{Field: strings.TrimSpace(raw)}
+			if strings.HasPrefix(field.Field, "-") {
+				field.Field = field.Field[1:]
+				field.Desc = true
+			}
+			if !allowed[field.Field] {
+				return PageRequest{}, fmt.Errorf("cannot sort by %s", field.Field)
+			}
+			page.OrderBy = append(page.OrderBy, field)
+		}
+	}
+	return page.Normalize(), nil
+}
+
+// EncodeCursor packs the ordering values of the last row of a page into an
+// opaque, URL-safe cursor.
+func EncodeCursor(values ...any) (string, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("error encoding cursor: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor unpacks a cursor created by EncodeCursor. It fails when the
+// cursor is malformed or holds no values. Integers come back as int64, so ids
+// above 2^53 are not rounded, and other numbers as float64.
+func DecodeCursor(cursor string) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var values []any
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid cursor: trailing data")
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("invalid cursor: no values")
+	}
+	for i, v := range values {
+		n, ok := v.(json.Number)
+		if !ok {
+			continue
+		}
+		if values[i], err = n.Int64(); err != nil {
+			if values[i], err = n.Float64(); err != nil {
+				return nil, fmt.Errorf("invalid cursor: %v", err)
+			}
+		}
+	}
+	return values, nil
+}
+
+// OrderByClause renders fields as an ORDER BY clause, or "" when fields is empty.
+func OrderByClause(fields []OrderField) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = pgx.Identifier{f.Field}.Sanitize()
+		if f.Desc {
+			parts[i] += " DESC"
+		} else {
+			parts[i] += " ASC"
+		}
+	}
+	return "ORDER BY " + strings.Join(parts, ", ")
+}
+
+// KeysetCondition builds the WHERE condition selecting the rows that come after
+// values in the given order. Placeholders start at $argOffset+1.
+//
+// For ORDER BY a ASC, b DESC it renders:
+//
+//	("a" > $1) OR ("a" = $1 AND "b" < $2)
+func KeysetCondition(order []OrderField, values []any, argOffset int) (string, []any, error) {
+	if len(order) != len(values) {
+		return "", nil, fmt.Errorf("cursor has %d values but order has %d fields", len(values), len(order))
+	}
+	var ors []string
+	for i, f := range order {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = $%d", pgx.Identifier{order[j].Field}.Sanitize(), argOffset+j+1))
+		}
+		op := ">"
+		if f.Desc {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s $%d", pgx.Identifier{f.Field}.Sanitize(), op, argOffset+i+1))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR "), values, nil
+}
+
+// GetPage runs GetPageCtx with a background context.
+func (r *Repository[P]) GetPage(filters map[string]any, page PageRequest) models.APIResponse {
+	return r.GetPageCtx(context.Background(), filters, page)
+}
+
+// GetPageCtx fetches one page of the rows matching filters (all rows when
+// filters is nil). The parser must implement PagedQueryParser.
+//
+// The SuccessDetails Data holds the page items and Page holds the total count,
+// the next cursor and whether more results are available. An invalid cursor
+// answers 400 (see CursorValidator).
+func (r *Repository[P]) GetPageCtx(ctx context.Context, filters map[string]any, page PageRequest) models.APIResponse {
+	parser, ok := any(r.parser).(PagedQueryParser)
+	if !ok {
+		return models.NewProblemDetails(500, "Pagination Not Supported", fmt.Sprintf("%T does not implement PagedQueryParser", r.parser), "repository.GetPage")
+	}
+	page = page.Normalize()
+	if page.Cursor != "" {
+		var err error
+		if validator, ok := any(parser).(CursorValidator); ok {
+			err = validator.ValidateCursor(page)
+		} else {
+			_, err = DecodeCursor(page.Cursor)
+		}
+		if err != nil {
+			return models.NewProblemDetails(400, "Invalid Cursor", err.Error(), "repository.GetPage")
+		}
+	}
+
+	db := r.reader(ctx)
+	countQuery, countArgs := parser.CountQuery(filters)
+	var total int64
+	if err := db.QueryRow(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return MapError(ctx, err, "Count Failed", "repository.GetPage")
+	}
+
+	query, args := parser.GetQueryPage(filters, page)
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return MapError(ctx, err, "GetPage Failed", "repository.GetPage")
+	}
+	defer rows.Close()
+
+	results, err := parser.ScanRows(rows)
+	if err != nil {
+		return MapError(ctx, err, "Scan Failed", "repository.GetPage")
+	}
+
+	info := models.PageInfo{Limit: page.Limit, Offset: page.Offset, Total: total}
+	if page.Cursor != "" {
+		info.Offset = 0
+		if info.NextCursor, err = parser.NextCursor(results, page); err != nil {
+			return models.NewProblemDetails(500, "Cursor Failed", err.Error(), "repository.GetPage")
+		}
+		info.HasMore = info.NextCursor != ""
+	} else {
+		info.HasMore = int64(page.Offset+page.Limit) < total
+		if info.HasMore {
+			// Also hand out a cursor so clients can switch to keyset paging.
+			if info.NextCursor, err = parser.NextCursor(results, page); err != nil {
+				return models.NewProblemDetails(500, "Cursor Failed", err.Error(), "repository.GetPage")
+			}
+		}
+	}
+
+	jsonStr, jsonErr := results.ToJSON()
+	if jsonErr != nil {
+		return models.NewProblemDetails(500, "Serialization Failed", jsonErr.Error(), "repository.GetPage")
+	}
+
+	return models.NewPagedSuccessDetails(200, "Fetched Page", "Resources fetched successfully", "repository.GetPage", jsonStr, info)
+}
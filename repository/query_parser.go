@@ -7,6 +7,8 @@ import (
 
 // QueryParser defines an interface for building SQL queries from custom input.
 // Each method should return the SQL query string and the arguments to bind.
+// UpdateQuery may return an empty query when there is nothing to update, which
+// the Repository reports as an error instead of running it.
 type QueryParser interface {
 	InsertQuery(data any) (string, []any)
 	UpdateQuery(data any) (string, []any)
@@ -16,3 +18,57 @@ type QueryParser interface {
 	ScanRow(row pgx.Row) (models.Model, error)
 	ScanRows(rows pgx.Rows) (models.Model, error)
 }
+
+// AuditedParser is implemented by parsers that maintain audit columns
+// (created_at, updated_at, updated_by). When the parser of a Repository
+// implements it, InsertCtx and UpdateCtx call these methods with the acting
+// user taken from the context (see ActorFromContext) instead of InsertQuery
+// and UpdateQuery.
+type AuditedParser interface {
+	InsertQueryBy(data any, actor string) (string, []any)
+	UpdateQueryBy(data any, actor string) (string, []any)
+}
+
+// SoftDeleteParser is implemented by parsers that can mark rows as deleted
+// instead of removing them. When SoftDeletes reports true, DeleteCtx runs
+// SoftDeleteQueryMany and RestoreCtx runs RestoreQueryMany; the parser is
+// expected to leave soft deleted rows out of its get queries.
+type SoftDeleteParser interface {
+	SoftDeletes() bool
+	SoftDeleteQueryMany(filters map[string]any, actor string) (string, []any)
+	RestoreQueryMany(filters map[string]any, actor string) (string, []any)
+}
+
+// VersionedParser is implemented by parsers with an optimistic locking version
+// column. Their update query must only match the row when its version equals
+// the one held by data, and increment it.
+//
+//   - Version returns the version held by data, false when there is no version column.
+//   - WithVersion returns a copy of data holding version.
+//   - ExistsQuery selects 1 if the row identified by data exists, so a failed
+//     update can be told apart between a missing row and a version mismatch.
+type VersionedParser interface {
+	Version(data any) (int64, bool)
+	WithVersion(data any, version int64) any
+	ExistsQuery(data any) (string, []any)
+}
+
+// BulkParser is implemented by parsers that can load many rows at once with
+// the COPY protocol, used by InsertManyCtx.
+//
+//   - CopyTable and CopyColumns name the target of the COPY.
+//   - CopyRow returns the values of data in CopyColumns order, recording actor
+//     in the audit columns like AuditedParser does.
+type BulkParser interface {
+	CopyTable() pgx.Identifier
+	CopyColumns() []string
+	CopyRow(data any, actor string) ([]any, error)
+}
+
+// UpsertParser is implemented by parsers that can insert a row or update it
+// when it already exists, used by UpsertCtx and UpsertManyCtx. The query must
+// be an INSERT ... ON CONFLICT ... DO UPDATE returning a single boolean column
+// that is true when the row was inserted, e.g. RETURNING (xmax = 0).
+type UpsertParser interface {
+	UpsertQuery(data any, actor string) (string, []any)
+}
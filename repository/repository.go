@@ -2,18 +2,28 @@ package repository
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"fmt"
 
 	"github.com/Class-Connect-GRUPO-5/microservices-common/database"
 	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// executor is the subset of the pgx API shared by *pgxpool.Pool and pgx.Tx,
+// which lets a Repository run either directly on the pool or inside a Tx.
+type executor interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
 type Repository[P QueryParser] struct {
 	parser P
-	db     *pgxpool.Pool
+	db     executor
 }
 
 func NewRepository[P QueryParser](parser P) Repository[P] {
@@ -23,49 +33,155 @@ func NewRepository[P QueryParser](parser P) Repository[P] {
 	}
 }
 
-func isBadRequestError(err error) bool {
-	msg := err.Error()
-	return strings.Contains(msg, "invalid input") ||
-		strings.Contains(msg, "violates not-null constraint") ||
-		strings.Contains(msg, "violates check constraint") ||
-		strings.Contains(msg, "syntax error")
+// reader returns where read queries run: the bound transaction if there is
+// one, otherwise a healthy read replica unless ctx forces the primary (see
+// database.Reader and database.ForcePrimary).
+func (r *Repository[P]) reader(ctx context.Context) executor {
+	if _, inTx := r.db.(pgx.Tx); inTx {
+		return r.db
+	}
+	if pool := database.Reader(ctx); pool != nil {
+		return pool
+	}
+	return r.db
+}
+
+// contextProblem reports whether err was caused by the context being cancelled
+// or reaching its deadline, and if so returns the matching ProblemDetails:
+// 504 when the deadline expired and 499 when the caller went away.
+//
+// Cancellation is checked first: pgx reports an operation started on an
+// already cancelled context as a timeout too.
+func contextProblem(ctx context.Context, err error, instance string) (models.ProblemDetails, bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled) {
+		return models.ClientClosedRequest("Request cancelled before the database operation completed", instance), true
+	}
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return models.GatewayTimeout("Database operation timed out", instance), true
+	}
+	return models.ProblemDetails{}, false
+}
+
+// insertQuery builds the insert query, letting an AuditedParser record the
+// acting user.
+func (r *Repository[P]) insertQuery(ctx context.Context, data any) (string, []any) {
+	if audited, ok := any(r.parser).(AuditedParser); ok {
+		return audited.InsertQueryBy(data, ActorFromContext(ctx))
+	}
+	return r.parser.InsertQuery(data)
+}
+
+// updateQuery builds the update query, letting an AuditedParser record the
+// acting user.
+func (r *Repository[P]) updateQuery(ctx context.Context, data any) (string, []any) {
+	if audited, ok := any(r.parser).(AuditedParser); ok {
+		return audited.UpdateQueryBy(data, ActorFromContext(ctx))
+	}
+	return r.parser.UpdateQuery(data)
 }
 
+// Insert runs InsertCtx with a background context.
 func (r *Repository[P]) Insert(data any) models.APIResponse {
-	query, args := r.parser.InsertQuery(data)
-	_, err := r.db.Exec(context.Background(), query, args...)
+	return r.InsertCtx(context.Background(), data)
+}
+
+// InsertCtx executes the parser's insert query bound to ctx, so the statement
+// is aborted as soon as the request is cancelled or its deadline expires.
+func (r *Repository[P]) InsertCtx(ctx context.Context, data any) models.APIResponse {
+	query, args := r.insertQuery(ctx, data)
+	_, err := r.db.Exec(ctx, query, args...)
 	if err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
-			return models.NewProblemDetails(409, "Conflict", "Resource already exists", "repository.Insert")
-		}
-		if isBadRequestError(err) {
-			return models.NewProblemDetails(400, "Bad Request", err.Error(), "repository.Insert")
-		}
-		return models.NewProblemDetails(500, "Insert Failed", err.Error(), "repository.Insert")
+		return MapError(ctx, err, "Insert Failed", "repository.Insert")
 	}
 	return models.NewSuccessDetails(201, "Created", "Insert successful", "repository.Insert", "")
 }
 
+// Update runs UpdateCtx with a background context.
 func (r *Repository[P]) Update(data any) models.APIResponse {
-	query, args := r.parser.UpdateQuery(data)
-	tag, err := r.db.Exec(context.Background(), query, args...)
-	if err != nil {
-		if isBadRequestError(err) {
-			return models.NewProblemDetails(400, "Bad Request", err.Error(), "repository.Update")
+	return r.UpdateCtx(context.Background(), data)
+}
+
+// UpdateCtx executes the parser's update query bound to ctx.
+//
+// When the parser implements VersionedParser the update is optimistic: it only
+// applies if the row is still at the expected version, which is taken from ctx
+// (see ExpectedVersion) or else from data. A mismatch answers 412 Precondition
+// Failed when the version came from ctx (an If-Match header) and 409 Conflict
+// otherwise. On success Data holds the new version as {"version": n}.
+func (r *Repository[P]) UpdateCtx(ctx context.Context, data any) models.APIResponse {
+	versioned, isVersioned := any(r.parser).(VersionedParser)
+	var version int64
+	expected, fromCtx := ExpectedVersion(ctx)
+	if isVersioned {
+		if version, isVersioned = versioned.Version(data); isVersioned && fromCtx {
+			data = versioned.WithVersion(data, expected)
+			version = expected
 		}
-		return models.NewProblemDetails(500, "Update Failed", err.Error(), "repository.Update")
+	}
+
+	query, args := r.updateQuery(ctx, data)
+	if query == "" {
+		return models.NewProblemDetails(500, "Update Failed", fmt.Sprintf("%T has no column to update", data), "repository.Update")
+	}
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return MapError(ctx, err, "Update Failed", "repository.Update")
 	}
 	if tag.RowsAffected() == 0 {
+		if isVersioned {
+			return r.versionProblem(ctx, versioned, data, version, fromCtx)
+		}
 		return models.NewProblemDetails(404, "Not Found", "Resource not found", "repository.Update")
 	}
+	if isVersioned {
+		return models.NewSuccessDetails(200, "Updated", "Update successful", "repository.Update", fmt.Sprintf(`{"version":%d}`, version+1))
+	}
 	return models.NewSuccessDetails(200, "Updated", "Update successful", "repository.Update", "")
 }
 
+// versionProblem explains why a versioned update matched no row.
+func (r *Repository[P]) versionProblem(ctx context.Context, parser VersionedParser, data any, version int64, fromCtx bool) models.APIResponse {
+	query, args := parser.ExistsQuery(data)
+	var exists int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&exists); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.NewProblemDetails(404, "Not Found", "Resource not found", "repository.Update")
+		}
+		return MapError(ctx, err, "Update Failed", "repository.Update")
+	}
+	detail := fmt.Sprintf("Resource was modified by someone else, version %d is no longer current", version)
+	if fromCtx {
+		return models.PreconditionFailed(detail, "repository.Update")
+	}
+	return models.Conflict(detail, "repository.Update")
+}
+
+// Delete runs DeleteCtx with a background context.
 func (r *Repository[P]) Delete(filters map[string]any) models.APIResponse {
+	return r.DeleteCtx(context.Background(), filters)
+}
+
+// DeleteCtx executes the parser's delete query bound to ctx. When the parser
+// soft deletes (see SoftDeleteParser) the rows are marked as deleted instead.
+func (r *Repository[P]) DeleteCtx(ctx context.Context, filters map[string]any) models.APIResponse {
+	if soft, ok := any(r.parser).(SoftDeleteParser); ok && soft.SoftDeletes() {
+		query, args := soft.SoftDeleteQueryMany(filters, ActorFromContext(ctx))
+		return r.execDelete(ctx, query, args)
+	}
+	return r.HardDeleteCtx(ctx, filters)
+}
+
+// HardDeleteCtx removes the rows matching filters even when the parser soft
+// deletes.
+func (r *Repository[P]) HardDeleteCtx(ctx context.Context, filters map[string]any) models.APIResponse {
 	query, args := r.parser.DeleteQueryMany(filters)
-	tag, err := r.db.Exec(context.Background(), query, args...)
+	return r.execDelete(ctx, query, args)
+}
+
+func (r *Repository[P]) execDelete(ctx context.Context, query string, args []any) models.APIResponse {
+	tag, err := r.db.Exec(ctx, query, args...)
 	if err != nil {
-		return models.NewProblemDetails(500, "Delete Failed", err.Error(), "repository.Delete")
+		return MapError(ctx, err, "Delete Failed", "repository.Delete")
 	}
 	if tag.RowsAffected() == 0 {
 		return models.NewProblemDetails(404, "Not Found", "Resource not found", "repository.Delete")
@@ -73,20 +189,47 @@ func (r *Repository[P]) Delete(filters map[string]any) models.APIResponse {
 	return models.NewSuccessDetails(200, "Deleted", "Delete successful", "repository.Delete", "")
 }
 
+// Restore runs RestoreCtx with a background context.
+func (r *Repository[P]) Restore(filters map[string]any) models.APIResponse {
+	return r.RestoreCtx(context.Background(), filters)
+}
+
+// RestoreCtx undoes the soft delete of the rows matching filters. The parser
+// must implement SoftDeleteParser.
+func (r *Repository[P]) RestoreCtx(ctx context.Context, filters map[string]any) models.APIResponse {
+	soft, ok := any(r.parser).(SoftDeleteParser)
+	if !ok || !soft.SoftDeletes() {
+		return models.NewProblemDetails(500, "Restore Not Supported", fmt.Sprintf("%T does not soft delete", r.parser), "repository.Restore")
+	}
+	query, args := soft.RestoreQueryMany(filters, ActorFromContext(ctx))
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return MapError(ctx, err, "Restore Failed", "repository.Restore")
+	}
+	if tag.RowsAffected() == 0 {
+		return models.NewProblemDetails(404, "Not Found", "Resource not found", "repository.Restore")
+	}
+	return models.NewSuccessDetails(200, "Restored", "Restore successful", "repository.Restore", "")
+}
+
+// GetByMany runs GetByManyCtx with a background context.
 func (r *Repository[P]) GetByMany(filters map[string]any) models.APIResponse {
+	return r.GetByManyCtx(context.Background(), filters)
+}
+
+// GetByManyCtx executes the parser's filtered select query bound to ctx. Like
+// every read it runs on a read replica when one is healthy (see reader).
+func (r *Repository[P]) GetByManyCtx(ctx context.Context, filters map[string]any) models.APIResponse {
 	query, args := r.parser.GetQueryMany(filters)
-	rows, err := r.db.Query(context.Background(), query, args...)
+	rows, err := r.reader(ctx).Query(ctx, query, args...)
 	if err != nil {
-		return models.NewProblemDetails(500, "GetByMany Failed", err.Error(), "repository.GetByMany")
+		return MapError(ctx, err, "GetByMany Failed", "repository.GetByMany")
 	}
 	defer rows.Close()
 
 	results, err := r.parser.ScanRows(rows)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return models.NewProblemDetails(404, "Not Found", "Resource not found", "repository.Get")
-		}
-		return models.NewProblemDetails(500, "Scan Failed", err.Error(), "repository.GetByMany")
+		return MapError(ctx, err, "Scan Failed", "repository.GetByMany")
 	}
 
 	jsonStr, jsonErr := results.ToJSON()
@@ -97,17 +240,24 @@ func (r *Repository[P]) GetByMany(filters map[string]any) models.APIResponse {
 	return models.NewSuccessDetails(200, "Fetched", "Resources fetched successfully", "repository.GetByMany", jsonStr)
 }
 
+// GetAll runs GetAllCtx with a background context.
 func (r *Repository[P]) GetAll() models.APIResponse {
+	return r.GetAllCtx(context.Background())
+}
+
+// GetAllCtx executes the parser's select-all query bound to ctx, on a read
+// replica when one is healthy.
+func (r *Repository[P]) GetAllCtx(ctx context.Context) models.APIResponse {
 	query, args := r.parser.GetAllQuery()
-	rows, err := r.db.Query(context.Background(), query, args...)
+	rows, err := r.reader(ctx).Query(ctx, query, args...)
 	if err != nil {
-		return models.NewProblemDetails(500, "GetAll Failed", err.Error(), "repository.GetAll")
+		return MapError(ctx, err, "GetAll Failed", "repository.GetAll")
 	}
 	defer rows.Close()
 
 	results, err := r.parser.ScanRows(rows)
 	if err != nil {
-		return models.NewProblemDetails(500, "Scan Failed", err.Error(), "repository.GetAll")
+		return MapError(ctx, err, "Scan Failed", "repository.GetAll")
 	}
 
 	jsonStr, jsonErr := results.ToJSON()
@@ -0,0 +1,755 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tabler can be implemented by a StructParser entity to set its table name.
+// Without it the table name is the snake_case, pluralized type name
+// (CourseEnrollment -> course_enrollments).
+type Tabler interface {
+	TableName() string
+}
+
+// Records is the models.Model returned by StructParser.ScanRows.
+type Records[T any] []T
+
+// ToJSON serializes the records as a JSON array.
+func (r Records[T]) ToJSON() (string, error) {
+	if r == nil {
+		r = Records[T]{}
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Audit and soft delete columns recognized by name by StructParser.
+const (
+	createdAtColumn = "created_at"
+	updatedAtColumn = "updated_at"
+	updatedByColumn = "updated_by"
+	deletedAtColumn = "deleted_at"
+)
+
+// column is a struct field mapped to a table column.
+type column struct {
+	name     string
+	index    []int
+	pk       bool
+	readonly bool
+	version  bool
+}
+
+// StructParser is a QueryParser (and PagedQueryParser) derived from the `db`
+// struct tags of T, so a new table only needs a struct definition:
+//
+//	type Course struct {
+//	    ID        string    `db:"id,pk,readonly"`
+//	    Name      string    `db:"name"`
+//	    CreatedAt time.Time `db:"created_at,readonly"`
+//	}
+//
+//	courses := repository.NewRepository(repository.NewStructParser[Course]())
+//
+// Tag options:
+//   - pk: the column is (part of) the primary key, used by UpdateQuery.
+//   - readonly: the column is filled by the database and never written.
+//   - version: an integer optimistic locking column. Inserts start it at 1 when
+//     zero, updates only match the row while it still holds the value in data
+//     and increment it (see VersionedParser).
+//
+// Bulk loads use COPY (see BulkParser) and upserts resolve conflicts on the
+// primary key unless WithConflict names another unique key.
+//
+// Exported fields without a tag use their snake_case name, fields tagged
+// `db:"-"` are ignored and embedded structs are flattened.
+//
+// Some column names are maintained automatically unless tagged readonly:
+// created_at and updated_at are set to now() on insert, updated_at is set to
+// now() on every update, and updated_by is set to the acting user (see
+// ActorFromContext). When T has a deleted_at column the parser soft deletes:
+// Repository.DeleteCtx sets deleted_at instead of removing the row, get
+// queries skip deleted rows and Repository.RestoreCtx clears it again.
+type StructParser[T any] struct {
+	table       string
+	columns     []column
+	withDeleted bool
+	conflict    []string
+}
+
+// NewStructParser builds the parser for T. It panics if T is not a struct or
+// declares no primary key, since that is a programming error.
+func NewStructParser[T any]() StructParser[T] {
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("repository: StructParser requires a struct type, got %v", typ))
+	}
+
+	p := StructParser[T]{columns: structColumns(typ, nil)}
+	if tabler, ok := any(zero).(Tabler); ok {
+		p.table = tabler.TableName()
+	} else if tabler, ok := any(&zero).(Tabler); ok {
+		p.table = tabler.TableName()
+	} else {
+		p.table = toSnakeCase(typ.Name()) + "s"
+	}
+
+	if len(p.primaryKeys()) == 0 {
+		panic(fmt.Sprintf("repository: %s has no field tagged as pk", typ.Name()))
+	}
+	return p
+}
+
+func structColumns(typ reflect.Type, parent []int) []column {
+	var columns []column
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		tag, hasTag := field.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && !hasTag {
+			columns = append(columns, structColumns(field.Type, index)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = toSnakeCase(field.Name)
+		}
+		col := column{name: name, index: index}
+		for _, opt := range strings.Split(opts, ",") {
+			switch strings.TrimSpace(opt) {
+			case "pk":
+				col.pk = true
+			case "readonly":
+				col.readonly = true
+			case "version":
+				col.version = true
+			}
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Break before an uppercase letter that starts a new word:
+			// CourseID -> course_id, HTTPServer -> http_server.
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// WithDeleted returns a copy of the parser whose get queries also return soft
+// deleted rows, e.g. for administration listings.
+func (p StructParser[T]) WithDeleted() StructParser[T] {
+	p.withDeleted = true
+	return p
+}
+
+// WithConflict returns a copy of the parser whose UpsertQuery resolves
+// conflicts on columns, which must form a unique constraint, instead of the
+// primary key. For example an enrollment imported by
+// WithConflict("course_id", "student_id").
+func (p StructParser[T]) WithConflict(columns ...string) StructParser[T] {
+	p.conflict = columns
+	return p
+}
+
+// SoftDeletes reports whether T has a deleted_at column.
+func (p StructParser[T]) SoftDeletes() bool {
+	_, ok := p.column(deletedAtColumn)
+	return ok
+}
+
+// writable reports whether the audit column name exists and may be written.
+func (p StructParser[T]) writable(name string) bool {
+	c, ok := p.column(name)
+	return ok && !c.readonly
+}
+
+// where renders filters plus, for soft deleting parsers, the condition that
+// skips deleted rows. It returns "" when there is no condition at all.
+func (p StructParser[T]) where(filters map[string]any, argOffset int) (string, []any) {
+	where, args := whereEquals(filters, argOffset)
+	if p.SoftDeletes() && !p.withDeleted {
+		alive := pgx.Identifier{deletedAtColumn}.Sanitize() + " IS NULL"
+		if where == "" {
+			where = alive
+		} else {
+			where += " AND " + alive
+		}
+	}
+	return where, args
+}
+
+// Table returns the quoted table name.
+func (p StructParser[T]) Table() string {
+	return pgx.Identifier{p.table}.Sanitize()
+}
+
+func (p StructParser[T]) primaryKeys() []column {
+	var pks []column
+	for _, c := range p.columns {
+		if c.pk {
+			pks = append(pks, c)
+		}
+	}
+	return pks
+}
+
+func (p StructParser[T]) selectList() string {
+	names := make([]string, len(p.columns))
+	for i, c := range p.columns {
+		names[i] = pgx.Identifier{c.name}.Sanitize()
+	}
+	return strings.Join(names, ", ")
+}
+
+// value returns the struct behind data, which must be a T or a *T.
+func (p StructParser[T]) value(data any) reflect.Value {
+	v, err := p.valueOf(data)
+	if err != nil {
+		panic("repository: " + err.Error())
+	}
+	return v
+}
+
+// valueOf is value returning an error instead of panicking, for callers that
+// run outside the request goroutine.
+func (p StructParser[T]) valueOf(data any) (reflect.Value, error) {
+	switch v := data.(type) {
+	case T:
+		return reflect.ValueOf(v), nil
+	case *T:
+		if v != nil {
+			return reflect.ValueOf(v).Elem(), nil
+		}
+	}
+	var zero T
+	return reflect.Value{}, fmt.Errorf("StructParser[%T] cannot handle %T", zero, data)
+}
+
+// whereEquals renders filters as equality conditions joined with AND, with
+// placeholders starting at $argOffset+1. A nil value renders IS NULL and a
+// slice renders = ANY(...). Keys are sorted so queries are deterministic.
+func whereEquals(filters map[string]any, argOffset int) (string, []any) {
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conds := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys))
+	for _, k := range keys {
+		ident := pgx.Identifier{k}.Sanitize()
+		v := filters[k]
+		switch {
+		case v == nil:
+			conds = append(conds, ident+" IS NULL")
+		case reflect.TypeOf(v).Kind() == reflect.Slice && reflect.TypeOf(v).Elem().Kind() != reflect.Uint8:
+			args = append(args, v)
+			conds = append(conds, fmt.Sprintf("%s = ANY($%d)", ident, argOffset+len(args)))
+		default:
+			args = append(args, v)
+			conds = append(conds, fmt.Sprintf("%s = $%d", ident, argOffset+len(args)))
+		}
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// InsertQuery inserts every non-readonly column of data (a T or *T).
+func (p StructParser[T]) InsertQuery(data any) (string, []any) {
+	return p.InsertQueryBy(data, "")
+}
+
+// InsertQueryBy is InsertQuery recording actor in updated_by, implementing
+// AuditedParser. An empty actor keeps the value held by data.
+func (p StructParser[T]) InsertQueryBy(data any, actor string) (string, []any) {
+	v := p.value(data)
+	var names, params []string
+	var args []any
+	for _, c := range p.columns {
+		if c.readonly || c.name == deletedAtColumn {
+			continue
+		}
+		names = append(names, pgx.Identifier{c.name}.Sanitize())
+		switch {
+		case c.name == createdAtColumn || c.name == updatedAtColumn:
+			params = append(params, "now()")
+			continue
+		case c.name == updatedByColumn && actor != "":
+			args = append(args, actor)
+		case c.version && v.FieldByIndex(c.index).IsZero():
+			params = append(params, "1")
+			continue
+		default:
+			args = append(args, v.FieldByIndex(c.index).Interface())
+		}
+		params = append(params, fmt.Sprintf("$%d", len(args)))
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", p.Table(), strings.Join(names, ", "), strings.Join(params, ", "))
+	return query, args
+}
+
+// UpdateQuery updates every non-readonly, non-pk column of the row matching
+// the primary key of data (a T or *T). Soft deleted rows are not updated, and
+// with a version column only the row still at data's version is. It returns
+// an empty query when T has no column to update.
+func (p StructParser[T]) UpdateQuery(data any) (string, []any) {
+	return p.UpdateQueryBy(data, "")
+}
+
+// UpdateQueryBy is UpdateQuery recording actor in updated_by, implementing
+// AuditedParser. An empty actor keeps the value held by data.
+func (p StructParser[T]) UpdateQueryBy(data any, actor string) (string, []any) {
+	v := p.value(data)
+	var sets, conds []string
+	var args []any
+	for _, c := range p.columns {
+		if c.readonly || c.pk || c.name == createdAtColumn || c.name == deletedAtColumn {
+			continue
+		}
+		ident := pgx.Identifier{c.name}.Sanitize()
+		switch {
+		case c.name == updatedAtColumn:
+			sets = append(sets, ident+" = now()")
+			continue
+		case c.version:
+			sets = append(sets, ident+" = "+ident+" + 1")
+			args = append(args, v.FieldByIndex(c.index).Interface())
+			conds = append(conds, fmt.Sprintf("%s = $%d", ident, len(args)))
+			continue
+		case c.name == updatedByColumn && actor != "":
+			args = append(args, actor)
+		default:
+			args = append(args, v.FieldByIndex(c.index).Interface())
+		}
+		sets = append(sets, fmt.Sprintf("%s = $%d", ident, len(args)))
+	}
+	if len(sets) == 0 {
+		return "", nil
+	}
+	pkConds, pkArgs := p.wherePrimaryKey(v, len(args))
+	conds = append(conds, pkConds)
+	args = append(args, pkArgs...)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", p.Table(), strings.Join(sets, ", "), strings.Join(conds, " AND "))
+	return query, args
+}
+
+// wherePrimaryKey renders the condition matching the live row with the primary
+// key held by v, with placeholders starting at $argOffset+1.
+func (p StructParser[T]) wherePrimaryKey(v reflect.Value, argOffset int) (string, []any) {
+	var conds []string
+	var args []any
+	for _, c := range p.primaryKeys() {
+		args = append(args, v.FieldByIndex(c.index).Interface())
+		conds = append(conds, fmt.Sprintf("%s = $%d", pgx.Identifier{c.name}.Sanitize(), argOffset+len(args)))
+	}
+	if p.SoftDeletes() {
+		conds = append(conds, pgx.Identifier{deletedAtColumn}.Sanitize()+" IS NULL")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// versionColumn returns the column tagged as version, if any.
+func (p StructParser[T]) versionColumn() (column, bool) {
+	for _, c := range p.columns {
+		if c.version {
+			return c, true
+		}
+	}
+	return column{}, false
+}
+
+// Version returns the version held by data, implementing VersionedParser. It
+// returns false when T has no version column.
+func (p StructParser[T]) Version(data any) (int64, bool) {
+	c, ok := p.versionColumn()
+	if !ok {
+		return 0, false
+	}
+	field := p.value(data).FieldByIndex(c.index)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(field.Uint()), true
+	}
+	return 0, false
+}
+
+// WithVersion returns a copy of data, as a T, holding version in its version
+// column, implementing VersionedParser.
+func (p StructParser[T]) WithVersion(data any, version int64) any {
+	item := p.value(data).Interface().(T)
+	c, ok := p.versionColumn()
+	if !ok {
+		return item
+	}
+	field := reflect.ValueOf(&item).Elem().FieldByIndex(c.index)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(version)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(version))
+	}
+	return item
+}
+
+// ExistsQuery selects 1 when the live row with data's primary key exists,
+// implementing VersionedParser.
+func (p StructParser[T]) ExistsQuery(data any) (string, []any) {
+	where, args := p.wherePrimaryKey(p.value(data), 0)
+	return fmt.Sprintf("SELECT 1 FROM %s WHERE %s", p.Table(), where), args
+}
+
+// CopyTable returns the table name, implementing BulkParser.
+func (p StructParser[T]) CopyTable() pgx.Identifier {
+	return pgx.Identifier{p.table}
+}
+
+// CopyColumns returns the columns written by InsertQuery, implementing
+// BulkParser.
+func (p StructParser[T]) CopyColumns() []string {
+	var names []string
+	for _, c := range p.columns {
+		if !c.readonly && c.name != deletedAtColumn {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// CopyRow returns the values of data (a T or *T) in CopyColumns order,
+// implementing BulkParser. Audit and version columns get the values
+// InsertQueryBy would give them.
+func (p StructParser[T]) CopyRow(data any, actor string) ([]any, error) {
+	v, err := p.valueOf(data)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var values []any
+	for _, c := range p.columns {
+		if c.readonly || c.name == deletedAtColumn {
+			continue
+		}
+		field := v.FieldByIndex(c.index)
+		switch {
+		case c.name == createdAtColumn || c.name == updatedAtColumn:
+			values = append(values, now)
+		case c.name == updatedByColumn && actor != "":
+			values = append(values, actor)
+		case c.version && field.IsZero():
+			values = append(values, 1)
+		default:
+			values = append(values, field.Interface())
+		}
+	}
+	return values, nil
+}
+
+// UpsertQuery inserts data (a T or *T) or, when the primary key (or the
+// WithConflict columns) already exists, updates the row the way UpdateQueryBy
+// would, reviving it if it was soft deleted. It returns whether the row was
+// inserted, implementing UpsertParser.
+func (p StructParser[T]) UpsertQuery(data any, actor string) (string, []any) {
+	query, args := p.InsertQueryBy(data, actor)
+
+	conflict := p.conflict
+	if len(conflict) == 0 {
+		for _, c := range p.primaryKeys() {
+			conflict = append(conflict, c.name)
+		}
+	}
+	isConflict := make(map[string]bool, len(conflict))
+	targets := make([]string, len(conflict))
+	for i, name := range conflict {
+		isConflict[name] = true
+		targets[i] = pgx.Identifier{name}.Sanitize()
+	}
+
+	var sets []string
+	for _, c := range p.columns {
+		if c.readonly || c.pk || isConflict[c.name] || c.name == createdAtColumn {
+			continue
+		}
+		ident := pgx.Identifier{c.name}.Sanitize()
+		switch {
+		case c.name == deletedAtColumn:
+			sets = append(sets, ident+" = NULL")
+		case c.name == updatedAtColumn:
+			sets = append(sets, ident+" = now()")
+		case c.version:
+			sets = append(sets, fmt.Sprintf("%s = %s.%s + 1", ident, p.Table(), ident))
+		default:
+			sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", ident, ident))
+		}
+	}
+	if len(sets) == 0 {
+		// DO NOTHING would not return the row, so touch a key column instead.
+		sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", targets[0], targets[0]))
+	}
+
+	query += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s RETURNING (xmax = 0) AS inserted", strings.Join(targets, ", "), strings.Join(sets, ", "))
+	return query, args
+}
+
+// DeleteQueryMany deletes the rows matching filters. Empty filters delete
+// nothing instead of truncating the table.
+func (p StructParser[T]) DeleteQueryMany(filters map[string]any) (string, []any) {
+	if len(filters) == 0 {
+		return fmt.Sprintf("DELETE FROM %s WHERE FALSE", p.Table()), nil
+	}
+	where, args := whereEquals(filters, 0)
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", p.Table(), where), args
+}
+
+// SoftDeleteQueryMany marks the rows matching filters as deleted, implementing
+// SoftDeleteParser. Empty filters delete nothing.
+func (p StructParser[T]) SoftDeleteQueryMany(filters map[string]any, actor string) (string, []any) {
+	return p.setDeletedAt(filters, actor, "now()", "IS NULL")
+}
+
+// RestoreQueryMany clears deleted_at on the soft deleted rows matching
+// filters, implementing SoftDeleteParser.
+func (p StructParser[T]) RestoreQueryMany(filters map[string]any, actor string) (string, []any) {
+	return p.setDeletedAt(filters, actor, "NULL", "IS NOT NULL")
+}
+
+func (p StructParser[T]) setDeletedAt(filters map[string]any, actor, value, currentState string) (string, []any) {
+	sets := []string{pgx.Identifier{deletedAtColumn}.Sanitize() + " = " + value}
+	var args []any
+	if p.writable(updatedAtColumn) {
+		sets = append(sets, pgx.Identifier{updatedAtColumn}.Sanitize()+" = now()")
+	}
+	if p.writable(updatedByColumn) && actor != "" {
+		args = append(args, actor)
+		sets = append(sets, fmt.Sprintf("%s = $%d", pgx.Identifier{updatedByColumn}.Sanitize(), len(args)))
+	}
+
+	where := "FALSE"
+	if len(filters) > 0 {
+		var filterArgs []any
+		where, filterArgs = whereEquals(filters, len(args))
+		args = append(args, filterArgs...)
+		where += " AND " + pgx.Identifier{deletedAtColumn}.Sanitize() + " " + currentState
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", p.Table(), strings.Join(sets, ", "), where), args
+}
+
+// GetQueryMany selects the rows matching filters.
+func (p StructParser[T]) GetQueryMany(filters map[string]any) (string, []any) {
+	query := fmt.Sprintf("SELECT %s FROM %s", p.selectList(), p.Table())
+	where, args := p.where(filters, 0)
+	if where == "" {
+		return query, nil
+	}
+	return query + " WHERE " + where, args
+}
+
+// GetQueryFilter selects the rows matching filter, implementing
+// FilterParser. It fails if filter refers to a column T does not have.
+func (p StructParser[T]) GetQueryFilter(filter Filter) (string, []any, error) {
+	for _, field := range filter.Fields() {
+		if _, ok := p.column(field); !ok {
+			return "", nil, fmt.Errorf("unknown filter field %s", field)
+		}
+	}
+	where, args, err := filter.SQL(0)
+	if err != nil {
+		return "", nil, err
+	}
+	if alive, _ := p.where(nil, 0); alive != "" {
+		where = "(" + where + ") AND " + alive
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", p.selectList(), p.Table(), where), args, nil
+}
+
+// GetAllQuery selects every row.
+func (p StructParser[T]) GetAllQuery() (string, []any) {
+	return p.GetQueryMany(nil)
+}
+
+// scanTargets returns pointers to the fields of item in select list order.
+func (p StructParser[T]) scanTargets(item *T) []any {
+	v := reflect.ValueOf(item).Elem()
+	targets := make([]any, len(p.columns))
+	for i, c := range p.columns {
+		targets[i] = v.FieldByIndex(c.index).Addr().Interface()
+	}
+	return targets
+}
+
+// ScanRow scans a single row into a Records[T] of length one.
+func (p StructParser[T]) ScanRow(row pgx.Row) (models.Model, error) {
+	var item T
+	if err := row.Scan(p.scanTargets(&item)...); err != nil {
+		return nil, err
+	}
+	return Records[T]{item}, nil
+}
+
+// ScanRows scans every row into a Records[T].
+func (p StructParser[T]) ScanRows(rows pgx.Rows) (models.Model, error) {
+	items, err := p.ScanTyped(rows)
+	if err != nil {
+		return nil, err
+	}
+	return Records[T](items), nil
+}
+
+// ScanTyped scans every row into a T, implementing TypedParser[T].
+func (p StructParser[T]) ScanTyped(rows pgx.Rows) ([]T, error) {
+	items := []T{}
+	for rows.Next() {
+		var item T
+		if err := rows.Scan(p.scanTargets(&item)...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// CountQuery counts the rows matching filters.
+func (p StructParser[T]) CountQuery(filters map[string]any) (string, []any) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", p.Table())
+	where, args := p.where(filters, 0)
+	if where == "" {
+		return query, nil
+	}
+	return query + " WHERE " + where, args
+}
+
+// pageOrder returns page.OrderBy followed by any primary key column it does
+// not already include, so keyset pagination always has a total order.
+func (p StructParser[T]) pageOrder(page PageRequest) []OrderField {
+	order := append([]OrderField{}, page.OrderBy...)
+	for _, pk := range p.primaryKeys() {
+		present := false
+		for _, f := range order {
+			if f.Field == pk.name {
+				present = true
+				break
+			}
+		}
+		if !present {
+			order = append(order, OrderField{Field: pk.name})
+		}
+	}
+	return order
+}
+
+// GetQueryPage selects one page of the rows matching filters. When page.Cursor
+// is set it is decoded and applied as a keyset condition; an invalid cursor
+// matches no rows, which GetPageCtx prevents by checking it with
+// ValidateCursor first.
+func (p StructParser[T]) GetQueryPage(filters map[string]any, page PageRequest) (string, []any) {
+	order := p.pageOrder(page)
+	where, args := p.where(filters, 0)
+	conds := []string{}
+	if where != "" {
+		conds = append(conds, where)
+	}
+
+	if page.Cursor != "" {
+		values, err := DecodeCursor(page.Cursor)
+		keyset, keyArgs, keyErr := KeysetCondition(order, values, len(args))
+		if err != nil || keyErr != nil {
+			conds = append(conds, "FALSE")
+		} else {
+			conds = append(conds, "("+keyset+")")
+			args = append(args, keyArgs...)
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", p.selectList(), p.Table())
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " " + OrderByClause(order)
+	args = append(args, page.Limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+	if page.Cursor == "" && page.Offset > 0 {
+		args = append(args, page.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return query, args
+}
+
+// ValidateCursor reports whether page.Cursor decodes to one value per field
+// of the page order, implementing CursorValidator.
+func (p StructParser[T]) ValidateCursor(page PageRequest) error {
+	values, err := DecodeCursor(page.Cursor)
+	if err != nil {
+		return err
+	}
+	if _, _, err := KeysetCondition(p.pageOrder(page), values, 0); err != nil {
+		return fmt.Errorf("invalid cursor: %v", err)
+	}
+	return nil
+}
+
+// NextCursor encodes the ordering values of the last record, or returns ""
+// when results holds fewer records than the page limit.
+func (p StructParser[T]) NextCursor(results models.Model, page PageRequest) (string, error) {
+	records, ok := results.(Records[T])
+	if !ok {
+		return "", fmt.Errorf("unexpected results type %T", results)
+	}
+	if len(records) == 0 || len(records) < page.Limit {
+		return "", nil
+	}
+
+	last := reflect.ValueOf(records[len(records)-1])
+	order := p.pageOrder(page)
+	values := make([]any, len(order))
+	for i, f := range order {
+		col, ok := p.column(f.Field)
+		if !ok {
+			return "", fmt.Errorf("unknown order field %s", f.Field)
+		}
+		values[i] = last.FieldByIndex(col.index).Interface()
+	}
+	return EncodeCursor(values...)
+}
+
+func (p StructParser[T]) column(name string) (column, bool) {
+	for _, c := range p.columns {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return column{}, false
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/database"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tx is a unit of work shared by every repository bound to it through InTx.
+// Repositories with different QueryParser types can take part in the same Tx,
+// so their writes are committed or rolled back together.
+type Tx struct {
+	tx pgx.Tx
+}
+
+// beginner is implemented by *pgxpool.Pool (new transaction) and by pgx.Tx
+// (new savepoint), which is all runTx needs to open a unit of work.
+type beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// ResponseError carries a failed APIResponse out of a transaction callback,
+// so WithTx can return that same response after rolling back.
+type ResponseError struct {
+	Response models.APIResponse
+}
+
+func (e ResponseError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Response.GetStatus(), e.Response.GetTitle(), e.Response.GetData())
+}
+
+// Check converts an APIResponse into an error for use inside a transaction
+// callback. It returns nil for successful responses and a ResponseError for
+// any response with a status code of 400 or above.
+//
+// Example:
+//
+//	resp := repository.WithTx(ctx, func(tx repository.Tx) error {
+//	    courses := courseRepo.InTx(tx)
+//	    if err := repository.Check(courses.InsertCtx(ctx, course)); err != nil {
+//	        return err
+//	    }
+//	    enrollments := enrollmentRepo.InTx(tx)
+//	    return repository.Check(enrollments.InsertCtx(ctx, enrollment))
+//	})
+func Check(resp models.APIResponse) error {
+	if resp.GetStatus() < 400 {
+		return nil
+	}
+	return ResponseError{Response: resp}
+}
+
+// WithTx begins a transaction on database.DB and calls fn with it. The
+// transaction is committed when fn returns nil and rolled back when fn returns
+// an error or panics.
+//
+// The returned APIResponse is a 200 SuccessDetails on commit. On failure it is
+// the response wrapped by a ResponseError returned from fn, or a ProblemDetails
+// built from the underlying error with the same mapping used by Repository.
+func WithTx(ctx context.Context, fn func(tx Tx) error) models.APIResponse {
+	return runTx(ctx, database.DB, fn, "repository.WithTx")
+}
+
+// WithTx runs fn inside a savepoint nested in t. Rolling back the savepoint
+// only discards the work done by fn; the outer transaction stays usable.
+func (t Tx) WithTx(ctx context.Context, fn func(tx Tx) error) models.APIResponse {
+	return runTx(ctx, t.tx, fn, "repository.Savepoint")
+}
+
+// NewTx wraps a transaction begun outside this package, e.g. with pgx.BeginFunc
+// on a dedicated connection, so repositories can join it through InTx.
+func NewTx(tx pgx.Tx) Tx {
+	return Tx{tx: tx}
+}
+
+// InTx returns a copy of the repository that executes every query inside tx.
+func (r *Repository[P]) InTx(tx Tx) Repository[P] {
+	bound := *r
+	bound.db = tx.tx
+	return bound
+}
+
+func runTx(ctx context.Context, b beginner, fn func(tx Tx) error, instance string) models.APIResponse {
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return txProblem(ctx, err, "Begin Failed", instance)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(p)
+		}
+	}()
+
+	if err := fn(Tx{tx: tx}); err != nil {
+		// Roll back even when ctx is already done, otherwise the connection
+		// would be returned to the pool in an aborted state.
+		_ = tx.Rollback(context.WithoutCancel(ctx))
+		return txProblem(ctx, err, "Transaction Failed", instance)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return txProblem(ctx, err, "Commit Failed", instance)
+	}
+	return models.NewSuccessDetails(200, "Committed", "Transaction committed successfully", instance, "")
+}
+
+func txProblem(ctx context.Context, err error, title, instance string) models.APIResponse {
+	var respErr ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.Response
+	}
+	return MapError(ctx, err, title, instance)
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// TypedParser is a QueryParser that can also scan rows into values of T,
+// avoiding the round trip through models.Model and JSON strings.
+type TypedParser[T any] interface {
+	QueryParser
+	ScanTyped(rows pgx.Rows) ([]T, error)
+}
+
+// TypedRepository returns query results as []T instead of stringified JSON.
+// It embeds Repository, so write methods are unchanged, and its GetByMany and
+// GetAll variants are thin adapters over Find and FindAll for handlers that
+// still work with models.APIResponse.
+type TypedRepository[P TypedParser[T], T any] struct {
+	Repository[P]
+}
+
+// NewTypedRepository creates a TypedRepository bound to database.DB.
+func NewTypedRepository[P TypedParser[T], T any](parser P) TypedRepository[P, T] {
+	return TypedRepository[P, T]{Repository: NewRepository(parser)}
+}
+
+// NewStructRepository creates a TypedRepository backed by a StructParser[T].
+//
+// Example:
+//
+//	courses := repository.NewStructRepository[Course]()
+//	list, problem := courses.Find(ctx, map[string]any{"teacher_id": id})
+func NewStructRepository[T any]() TypedRepository[StructParser[T], T] {
+	return NewTypedRepository[StructParser[T], T](NewStructParser[T]())
+}
+
+// InTx returns a copy of the repository that executes every query inside tx.
+func (r *TypedRepository[P, T]) InTx(tx Tx) TypedRepository[P, T] {
+	return TypedRepository[P, T]{Repository: r.Repository.InTx(tx)}
+}
+
+// Find returns the rows matching filters. An empty result is not an error.
+func (r *TypedRepository[P, T]) Find(ctx context.Context, filters map[string]any) ([]T, *models.ProblemDetails) {
+	query, args := r.parser.GetQueryMany(filters)
+	return r.query(ctx, query, args, "repository.Find")
+}
+
+// FindWhere returns the rows matching filter. The parser must implement
+// FilterParser; an invalid filter returns a 400 ProblemDetails.
+func (r *TypedRepository[P, T]) FindWhere(ctx context.Context, filter Filter) ([]T, *models.ProblemDetails) {
+	parser, ok := any(r.parser).(FilterParser)
+	if !ok {
+		problem := models.NewProblemDetails(500, "Filtering Not Supported", fmt.Sprintf("%T does not implement FilterParser", r.parser), "repository.FindWhere")
+		return nil, &problem
+	}
+	query, args, err := parser.GetQueryFilter(filter)
+	if err != nil {
+		problem := models.BadRequest(err.Error(), "repository.FindWhere")
+		return nil, &problem
+	}
+	return r.query(ctx, query, args, "repository.FindWhere")
+}
+
+// FindAll returns every row.
+func (r *TypedRepository[P, T]) FindAll(ctx context.Context) ([]T, *models.ProblemDetails) {
+	query, args := r.parser.GetAllQuery()
+	return r.query(ctx, query, args, "repository.FindAll")
+}
+
+// FindOne returns the first row matching filters, or a 404 ProblemDetails
+// when there is none.
+func (r *TypedRepository[P, T]) FindOne(ctx context.Context, filters map[string]any) (T, *models.ProblemDetails) {
+	var zero T
+	items, problem := r.Find(ctx, filters)
+	if problem != nil {
+		return zero, problem
+	}
+	if len(items) == 0 {
+		notFound := models.NewProblemDetails(404, "Not Found", "Resource not found", "repository.FindOne")
+		return zero, &notFound
+	}
+	return items[0], nil
+}
+
+func (r *TypedRepository[P, T]) query(ctx context.Context, query string, args []any, instance string) ([]T, *models.ProblemDetails) {
+	rows, err := r.reader(ctx).Query(ctx, query, args...)
+	if err != nil {
+		problem := MapError(ctx, err, "Query Failed", instance)
+		return nil, &problem
+	}
+	defer rows.Close()
+
+	items, err := r.parser.ScanTyped(rows)
+	if err != nil {
+		problem := MapError(ctx, err, "Scan Failed", instance)
+		return nil, &problem
+	}
+	return items, nil
+}
+
+// Respond adapts a typed result into the APIResponse handlers already use:
+// the problem when it is not nil, otherwise a 200 SuccessDetails carrying
+// items as JSON.
+func Respond[T any](items T, problem *models.ProblemDetails, title, instance string) models.APIResponse {
+	if problem != nil {
+		return *problem
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return models.NewProblemDetails(500, "Serialization Failed", err.Error(), instance)
+	}
+	return models.NewSuccessDetails(200, title, "Resources fetched successfully", instance, string(b))
+}
+
+// GetByMany runs GetByManyCtx with a background context.
+func (r *TypedRepository[P, T]) GetByMany(filters map[string]any) models.APIResponse {
+	return r.GetByManyCtx(context.Background(), filters)
+}
+
+// GetByManyCtx wraps Find in an APIResponse.
+func (r *TypedRepository[P, T]) GetByManyCtx(ctx context.Context, filters map[string]any) models.APIResponse {
+	items, problem := r.Find(ctx, filters)
+	return Respond(items, problem, "Fetched", "repository.GetByMany")
+}
+
+// GetAll runs GetAllCtx with a background context.
+func (r *TypedRepository[P, T]) GetAll() models.APIResponse {
+	return r.GetAllCtx(context.Background())
+}
+
+// GetAllCtx wraps FindAll in an APIResponse.
+func (r *TypedRepository[P, T]) GetAllCtx(ctx context.Context) models.APIResponse {
+	items, problem := r.FindAll(ctx)
+	return Respond(items, problem, "Fetched All", "repository.GetAll")
+}
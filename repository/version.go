@@ -0,0 +1,26 @@
+package repository
+
+import "context"
+
+// ExpectedVersionKey is the gin context key holding the row version the client
+// expects to update, set by middleware.IfMatch from the If-Match header.
+// utils.RequestContext carries it to Repository methods.
+const ExpectedVersionKey = "expected_version"
+
+type expectedVersionKey struct{}
+
+// WithExpectedVersion returns a copy of ctx requiring updates to apply only to
+// rows still at version, for callers that do not run inside a gin request.
+func WithExpectedVersion(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+// ExpectedVersion returns the version stored by WithExpectedVersion or by
+// middleware.IfMatch.
+func ExpectedVersion(ctx context.Context) (int64, bool) {
+	if version, ok := ctx.Value(expectedVersionKey{}).(int64); ok {
+		return version, true
+	}
+	version, ok := ctx.Value(ExpectedVersionKey).(int64)
+	return version, ok
+}
@@ -0,0 +1,35 @@
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/middleware"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActorFromContext_GinRequest(t *testing.T) {
+	r := setupGin()
+	secret := "test-secret"
+
+	var actor string
+	r.GET("/posts", middleware.SetJWTDataFromToken(secret), func(c *gin.Context) {
+		actor = repository.ActorFromContext(c)
+	})
+
+	req := httptest.NewRequest("GET", "/posts", nil)
+	req.Header.Set("Authorization", createAuthHeader("user123", "teacher", "t@test.com", "Teacher", secret))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "user123", actor)
+}
+
+func TestActorFromContext_WithActor(t *testing.T) {
+	ctx := repository.WithActor(context.Background(), "worker")
+
+	assert.Equal(t, "worker", repository.ActorFromContext(ctx))
+	assert.Equal(t, "", repository.ActorFromContext(context.Background()))
+}
@@ -3,20 +3,14 @@ package test
 import (
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 
-	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
 	"github.com/Class-Connect-GRUPO-5/microservices-common/middleware"
 	"github.com/Class-Connect-GRUPO-5/microservices-common/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
-func init() {
-	logger.InitLogger("auth", logger.Error, os.Stdout, true)
-}
-
 func setupGin() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
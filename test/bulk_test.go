@@ -0,0 +1,71 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type Enrollment struct {
+	ID        string `db:"id,pk,readonly"`
+	CourseID  string `db:"course_id"`
+	StudentID string `db:"student_id"`
+	Status    string `db:"status"`
+}
+
+func TestStructParser_UpsertQuery(t *testing.T) {
+	parser := repository.NewStructParser[Submission]()
+
+	query, args := parser.UpsertQuery(Submission{ID: "s1", Grade: 9, Version: 2}, "")
+
+	assert.Equal(t, `INSERT INTO "submissions" ("id", "grade", "version") VALUES ($1, $2, $3) ON CONFLICT ("id") DO UPDATE SET "grade" = EXCLUDED."grade", "version" = "submissions"."version" + 1 RETURNING (xmax = 0) AS inserted`, query)
+	assert.Equal(t, []any{"s1", 9, int64(2)}, args)
+}
+
+func TestStructParser_UpsertQueryWithConflict(t *testing.T) {
+	parser := repository.NewStructParser[Enrollment]().WithConflict("course_id", "student_id")
+
+	query, _ := parser.UpsertQuery(Enrollment{CourseID: "c1", StudentID: "u1", Status: "active"}, "")
+
+	assert.Equal(t, `INSERT INTO "enrollments" ("course_id", "student_id", "status") VALUES ($1, $2, $3) ON CONFLICT ("course_id", "student_id") DO UPDATE SET "status" = EXCLUDED."status" RETURNING (xmax = 0) AS inserted`, query)
+}
+
+func TestStructParser_CopyRow(t *testing.T) {
+	parser := repository.NewStructParser[ForumPost]()
+
+	row, err := parser.CopyRow(&ForumPost{ID: "p1", Body: "hi"}, "teacher-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, pgx.Identifier{"forum_posts"}, parser.CopyTable())
+	assert.Equal(t, []string{"id", "body", "created_at", "updated_at", "updated_by"}, parser.CopyColumns())
+	assert.Len(t, row, 5)
+	assert.Equal(t, "p1", row[0])
+	assert.Equal(t, "teacher-1", row[4])
+}
+
+func TestStructParser_CopyRowWrongType(t *testing.T) {
+	parser := repository.NewStructParser[ForumPost]()
+
+	_, err := parser.CopyRow(Enrollment{ID: "e1"}, "")
+	_, nilErr := parser.CopyRow((*ForumPost)(nil), "")
+
+	assert.ErrorContains(t, err, "cannot handle test.Enrollment")
+	assert.Error(t, nilErr)
+}
+
+func TestInsertMany_WrongElementType(t *testing.T) {
+	tx := &fakeTx{}
+	posts := repository.NewRepository(repository.NewStructParser[ForumPost]())
+	bound := posts.InTx(repository.NewTx(tx))
+
+	resp := bound.InsertManyCtx(context.Background(), []any{ForumPost{ID: "p1"}, Enrollment{ID: "e1"}})
+	ok := bound.InsertManyCtx(context.Background(), []ForumPost{{ID: "p1"}, {ID: "p2"}})
+
+	assert.Equal(t, 400, resp.GetStatus())
+	assert.Contains(t, resp.GetData(), "element 1")
+	assert.Equal(t, 201, ok.GetStatus())
+	assert.Len(t, tx.copied, 2, "nothing is copied from the rejected batch")
+}
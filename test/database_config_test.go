@@ -0,0 +1,58 @@
+package test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseConfig_RedactsPassword(t *testing.T) {
+	cfg := database.Config{Host: "db", Port: "5432", User: "app", Password: "s3cr3t!", Database: "users"}
+
+	assert.NotContains(t, cfg.String(), "s3cr3t")
+	assert.Contains(t, cfg.String(), "app:xxxxx@db:5432/users")
+	assert.Contains(t, cfg.DSN(), "sslmode=disable")
+}
+
+func TestDatabaseConfig_PoolConfig(t *testing.T) {
+	cfg := database.Config{
+		Host: "db", Port: "5432", User: "app", Password: "p@ss/word", Database: "users",
+		SSLMode: "require", ApplicationName: "users-service", MaxConns: 12, ConnectTimeout: 3 * time.Second,
+	}
+
+	poolConfig, err := cfg.PoolConfig()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(12), poolConfig.MaxConns)
+	assert.Equal(t, "p@ss/word", poolConfig.ConnConfig.Password)
+	assert.Equal(t, 3*time.Second, poolConfig.ConnConfig.ConnectTimeout)
+	assert.Equal(t, "users-service", poolConfig.ConnConfig.RuntimeParams["application_name"])
+}
+
+func TestDatabaseConfigFromEnv_InvalidValue(t *testing.T) {
+	t.Setenv("DATABASE_MAX_CONNS", "many")
+
+	_, err := database.ConfigFromEnv()
+
+	assert.Error(t, err)
+}
+
+func TestOpen_ZeroRetryBackoffWaitsBeforeRetrying(t *testing.T) {
+	// A port nobody listens on: every attempt fails at once.
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+	cfg := database.Config{Host: "127.0.0.1", Port: port, User: "app", Database: "users", ConnectTimeout: time.Second, ConnectRetries: 3}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	_, err := database.Open(ctx, cfg)
+
+	// Without the 1s default the retries would run back to back and give up
+	// before ctx expires.
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
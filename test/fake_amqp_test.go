@@ -0,0 +1,742 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAMQP is a minimal AMQP 0-9-1 server speaking just enough of the
+// protocol for a rabbitmq.Client: the handshake, channels, declarations and
+// their refusal, publisher confirms, consumers, basic.get, returns and
+// connection blocking. It records every declaration, binding, publish and
+// settlement, confirms publishes as ack decides and answers the requests
+// published to the queues in rpc. It does not route: tests hand messages to
+// consumers with deliver and to basic.get with store, and requeued messages
+// go back where they came from.
+type fakeAMQP struct {
+	listener net.Listener
+	received chan fakePublish
+
+	mu        sync.Mutex
+	conns     []*fakeAMQPConn
+	exchanges []fakeExchange
+	queues    []fakeQueue
+	bindings  []fakeBinding
+	published []fakePublish
+	settled   []fakeSettlement
+	consumers map[string]fakeConsumer
+	stored    map[string][]fakePublish
+	// ack decides whether a publish is acked or nacked; nil acks everything.
+	ack func(fakePublish) bool
+	// hold, when set, delays each confirmation until a value is received.
+	hold chan struct{}
+	// rpc answers requests by queue, with a reply body or an error message.
+	// A nil body without error leaves the request unanswered.
+	rpc map[string]func(fakePublish) ([]byte, string)
+	// refuse closes the channel declaring one of these exchanges with the
+	// given reply code, e.g. 406 PRECONDITION_FAILED.
+	refuse map[string]uint16
+}
+
+// fakeExchange is an exchange declared on a fakeAMQP.
+type fakeExchange struct {
+	Name       string
+	Kind       string
+	Durable    bool
+	AutoDelete bool
+	Args       map[string]any
+}
+
+// fakeQueue is a queue declared on a fakeAMQP.
+type fakeQueue struct {
+	Name    string
+	Durable bool
+	Args    map[string]any
+}
+
+// fakeSettlement is the ack, nack or reject of a message delivered by a
+// fakeAMQP.
+type fakeSettlement struct {
+	Queue   string
+	Body    string
+	Ack     bool
+	Requeue bool
+}
+
+// fakeConsumer is the channel consuming a queue.
+type fakeConsumer struct {
+	conn *fakeAMQPConn
+	id   uint16
+	ch   *fakeAMQPChannel
+	tag  string
+}
+
+// fakeBinding is a queue binding made on a fakeAMQP.
+type fakeBinding struct {
+	Queue    string
+	Exchange string
+	Key      string
+}
+
+// fakePublish is a message published to a fakeAMQP.
+type fakePublish struct {
+	Exchange      string
+	Key           string
+	Mandatory     bool
+	CorrelationId string
+	ReplyTo       string
+	Headers       map[string]any
+	Body          []byte
+
+	props []byte
+	size  uint64
+}
+
+func newFakeAMQP(t *testing.T) *fakeAMQP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &fakeAMQP{listener: listener, received: make(chan fakePublish, 100)}
+	t.Cleanup(func() {
+		listener.Close()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, c := range s.conns {
+			c.conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c := &fakeAMQPConn{server: s, conn: conn, channels: map[uint16]*fakeAMQPChannel{}}
+			s.mu.Lock()
+			s.conns = append(s.conns, c)
+			s.mu.Unlock()
+			go c.serve()
+		}
+	}()
+	return s
+}
+
+// config points config at s.
+func (s *fakeAMQP) config(config rabbitmq.Config) rabbitmq.Config {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	config.Host, config.Port = host, uint16(n)
+	return config
+}
+
+// client connects a rabbitmq.Client named "courses" to s.
+func (s *fakeAMQP) client(t *testing.T, config rabbitmq.Config) *rabbitmq.Client {
+	config = s.config(config)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	client, err := rabbitmq.NewClientContext(ctx, "courses", config, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// bodies returns the bodies of every message published so far, in order.
+func (s *fakeAMQP) bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bodies := []string{}
+	for _, p := range s.published {
+		bodies = append(bodies, string(p.Body))
+	}
+	return bodies
+}
+
+// declared returns every exchange declaration so far, in order.
+func (s *fakeAMQP) declared() []fakeExchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeExchange{}, s.exchanges...)
+}
+
+// bound returns every queue binding so far, in order.
+func (s *fakeAMQP) bound() []fakeBinding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeBinding{}, s.bindings...)
+}
+
+// declaredQueues returns every queue declaration so far, in order.
+func (s *fakeAMQP) declaredQueues() []fakeQueue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeQueue{}, s.queues...)
+}
+
+// settlements returns every ack, nack and reject so far, in order.
+func (s *fakeAMQP) settlements() []fakeSettlement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeSettlement{}, s.settled...)
+}
+
+// deliver hands p to the consumer of queue, reporting false if there is none.
+func (s *fakeAMQP) deliver(queue string, p fakePublish, redelivered bool) bool {
+	s.mu.Lock()
+	consumer, ok := s.consumers[queue]
+	s.mu.Unlock()
+	if ok {
+		consumer.conn.deliver(consumer.id, consumer.ch, consumer.tag, queue, p, redelivered)
+	}
+	return ok
+}
+
+// store adds p to queue, to be fetched with basic.get.
+func (s *fakeAMQP) store(queue string, p fakePublish) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stored == nil {
+		s.stored = map[string][]fakePublish{}
+	}
+	s.stored[queue] = append(s.stored[queue], p)
+}
+
+// storedIn returns the messages left in queue for basic.get.
+func (s *fakeAMQP) storedIn(queue string) []fakePublish {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakePublish{}, s.stored[queue]...)
+}
+
+// disconnect drops every connection, as a broker restart does.
+func (s *fakeAMQP) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.conn.Close()
+	}
+}
+
+// block blocks or unblocks every connection, as a broker under a resource
+// alarm does.
+func (s *fakeAMQP) block(active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		if active {
+			c.sendMethod(0, 10, 60, new(amqpWriter).shortstr("low on memory"))
+		} else {
+			c.sendMethod(0, 10, 61, new(amqpWriter))
+		}
+	}
+}
+
+type fakeAMQPConn struct {
+	server *fakeAMQP
+	conn   net.Conn
+	wmu    sync.Mutex
+
+	channels map[uint16]*fakeAMQPChannel
+}
+
+type fakeAMQPChannel struct {
+	confirm   bool
+	published uint64
+	replyTag  string
+	pending   *fakePublish
+
+	// mu guards the deliveries, also made by tests through fakeAMQP.deliver.
+	mu         sync.Mutex
+	delivered  uint64
+	deliveries map[uint64]fakeDelivery
+}
+
+// fakeDelivery is a message delivered on a channel and not settled yet.
+type fakeDelivery struct {
+	queue string
+	msg   fakePublish
+	get   bool
+}
+
+func (c *fakeAMQPConn) serve() {
+	defer c.conn.Close()
+	r := bufio.NewReader(c.conn)
+	if _, err := io.ReadFull(r, make([]byte, 8)); err != nil {
+		return
+	}
+	c.sendMethod(0, 10, 10, new(amqpWriter).octet(0).octet(9).table(nil).longstr("PLAIN").longstr("en_US"))
+
+	for {
+		var header [7]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[3:])+1)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+		id := binary.BigEndian.Uint16(header[1:3])
+		payload = payload[:len(payload)-1]
+		ch := c.channels[id]
+
+		switch header[0] {
+		case 1:
+			in := &amqpReader{b: payload}
+			if !c.handle(id, ch, in.short(), in.short(), in) {
+				return
+			}
+		case 2:
+			in := &amqpReader{b: payload}
+			in.short()
+			in.short()
+			ch.pending.size = in.longlong()
+			ch.pending.props = in.b
+			ch.pending.parseProps()
+			if ch.pending.size == 0 {
+				c.published(id, ch)
+			}
+		case 3:
+			ch.pending.Body = append(ch.pending.Body, payload...)
+			if uint64(len(ch.pending.Body)) >= ch.pending.size {
+				c.published(id, ch)
+			}
+		}
+	}
+}
+
+// handle answers one method, reporting false once the connection is closed.
+func (c *fakeAMQPConn) handle(id uint16, ch *fakeAMQPChannel, class, method uint16, in *amqpReader) bool {
+	switch {
+	case class == 10 && method == 11: // connection.start-ok
+		c.sendMethod(0, 10, 30, new(amqpWriter).short(0).long(131072).short(0))
+	case class == 10 && method == 40: // connection.open
+		c.sendMethod(0, 10, 41, new(amqpWriter).shortstr(""))
+	case class == 10 && method == 50: // connection.close
+		c.sendMethod(0, 10, 51, new(amqpWriter))
+		return false
+	case class == 20 && method == 10: // channel.open
+		c.channels[id] = &fakeAMQPChannel{}
+		c.sendMethod(id, 20, 11, new(amqpWriter).longstr(""))
+	case class == 20 && method == 40: // channel.close
+		c.requeueGets(ch)
+		delete(c.channels, id)
+		c.sendMethod(id, 20, 41, new(amqpWriter))
+	case class == 20 && method == 41: // channel.close-ok
+		delete(c.channels, id)
+	case class == 40 && method == 10: // exchange.declare
+		in.short()
+		exchange := fakeExchange{Name: in.shortstr(), Kind: in.shortstr()}
+		flags := in.octet()
+		exchange.Durable, exchange.AutoDelete = flags&2 != 0, flags&4 != 0
+		exchange.Args = in.table()
+		c.server.mu.Lock()
+		c.server.exchanges = append(c.server.exchanges, exchange)
+		code := c.server.refuse[exchange.Name]
+		c.server.mu.Unlock()
+		if code != 0 {
+			c.sendMethod(id, 20, 40, new(amqpWriter).short(code).shortstr("refused").short(40).short(10))
+			break
+		}
+		c.sendMethod(id, 40, 11, new(amqpWriter))
+	case class == 50 && method == 10: // queue.declare
+		in.short()
+		queue := fakeQueue{Name: in.shortstr()}
+		queue.Durable = in.octet()&2 != 0
+		queue.Args = in.table()
+		c.server.mu.Lock()
+		c.server.queues = append(c.server.queues, queue)
+		c.server.mu.Unlock()
+		c.sendMethod(id, 50, 11, new(amqpWriter).shortstr(queue.Name).long(0).long(0))
+	case class == 50 && method == 20: // queue.bind
+		in.short()
+		binding := fakeBinding{Queue: in.shortstr(), Exchange: in.shortstr(), Key: in.shortstr()}
+		c.server.mu.Lock()
+		c.server.bindings = append(c.server.bindings, binding)
+		c.server.mu.Unlock()
+		c.sendMethod(id, 50, 21, new(amqpWriter))
+	case class == 60 && method == 10: // basic.qos
+		c.sendMethod(id, 60, 11, new(amqpWriter))
+	case class == 60 && method == 20: // basic.consume
+		in.short()
+		queue := in.shortstr()
+		ch.replyTag = in.shortstr()
+		c.server.mu.Lock()
+		if c.server.consumers == nil {
+			c.server.consumers = map[string]fakeConsumer{}
+		}
+		c.server.consumers[queue] = fakeConsumer{conn: c, id: id, ch: ch, tag: ch.replyTag}
+		c.server.mu.Unlock()
+		c.sendMethod(id, 60, 21, new(amqpWriter).shortstr(ch.replyTag))
+	case class == 60 && method == 30: // basic.cancel
+		tag := in.shortstr()
+		c.server.mu.Lock()
+		for queue, consumer := range c.server.consumers {
+			if consumer.ch == ch && consumer.tag == tag {
+				delete(c.server.consumers, queue)
+			}
+		}
+		c.server.mu.Unlock()
+		c.sendMethod(id, 60, 31, new(amqpWriter).shortstr(tag))
+	case class == 60 && method == 70: // basic.get
+		in.short()
+		c.get(id, ch, in.shortstr())
+	case class == 60 && method == 80: // basic.ack
+		c.settle(ch, in.longlong(), true, false)
+	case class == 60 && method == 90: // basic.reject
+		c.settle(ch, in.longlong(), false, in.octet()&1 != 0)
+	case class == 60 && method == 120: // basic.nack
+		c.settle(ch, in.longlong(), false, in.octet()&2 != 0)
+	case class == 60 && method == 40: // basic.publish
+		in.short()
+		ch.pending = &fakePublish{Exchange: in.shortstr(), Key: in.shortstr()}
+		ch.pending.Mandatory = in.octet()&1 != 0
+	case class == 85 && method == 10: // confirm.select
+		ch.confirm = true
+		c.sendMethod(id, 85, 11, new(amqpWriter))
+	}
+	return true
+}
+
+// published records the message completed on ch, answers it if it is a
+// call, returns it if unroutable and confirms it.
+func (c *fakeAMQPConn) published(id uint16, ch *fakeAMQPChannel) {
+	p := *ch.pending
+	ch.pending = nil
+	s := c.server
+	s.mu.Lock()
+	s.published = append(s.published, p)
+	ack, hold, answer := s.ack == nil || s.ack(p), s.hold, s.rpc[p.Key]
+	s.mu.Unlock()
+	select {
+	case s.received <- p:
+	default:
+	}
+
+	switch {
+	case p.Exchange == "" && answer != nil:
+		if body, errMsg := answer(p); body != nil || errMsg != "" {
+			reply := fakePublish{Key: p.ReplyTo, CorrelationId: p.CorrelationId, Body: body}
+			if errMsg != "" {
+				reply.Headers = map[string]any{rabbitmq.ErrorHeader: errMsg}
+			}
+			c.deliver(id, ch, ch.replyTag, "", reply, false)
+		}
+	case p.Exchange == "" && p.Mandatory:
+		c.sendMethod(id, 60, 50, new(amqpWriter).short(312).shortstr("NO_ROUTE").shortstr(p.Exchange).shortstr(p.Key))
+		c.sendContent(id, p.props, p.Body)
+	}
+
+	if !ch.confirm {
+		return
+	}
+	ch.published++
+	confirm := func(tag uint64) {
+		if ack {
+			c.sendMethod(id, 60, 80, new(amqpWriter).longlong(tag).octet(0))
+		} else {
+			c.sendMethod(id, 60, 120, new(amqpWriter).longlong(tag).octet(0))
+		}
+	}
+	if hold == nil {
+		confirm(ch.published)
+		return
+	}
+	go func(tag uint64) {
+		<-hold
+		confirm(tag)
+	}(ch.published)
+}
+
+// deliver sends p, taken from queue, to the consumer tag on channel id.
+func (c *fakeAMQPConn) deliver(id uint16, ch *fakeAMQPChannel, tag, queue string, p fakePublish, redelivered bool) {
+	deliveryTag := ch.track(fakeDelivery{queue: queue, msg: p})
+	var flag byte
+	if redelivered {
+		flag = 1
+	}
+	c.sendMethod(id, 60, 60, new(amqpWriter).shortstr(tag).longlong(deliveryTag).octet(flag).shortstr(p.Exchange).shortstr(p.Key))
+	c.sendContent(id, p.encodeProps(), p.Body)
+}
+
+// get answers basic.get with the first message stored in queue.
+func (c *fakeAMQPConn) get(id uint16, ch *fakeAMQPChannel, queue string) {
+	s := c.server
+	s.mu.Lock()
+	messages := s.stored[queue]
+	if len(messages) == 0 {
+		s.mu.Unlock()
+		c.sendMethod(id, 60, 72, new(amqpWriter).shortstr(""))
+		return
+	}
+	p := messages[0]
+	s.stored[queue] = messages[1:]
+	s.mu.Unlock()
+
+	deliveryTag := ch.track(fakeDelivery{queue: queue, msg: p, get: true})
+	c.sendMethod(id, 60, 71, new(amqpWriter).longlong(deliveryTag).octet(0).shortstr(p.Exchange).shortstr(p.Key).long(uint32(len(messages)-1)))
+	c.sendContent(id, p.encodeProps(), p.Body)
+}
+
+// settle records the ack, nack or reject of a delivery, putting it back
+// where it came from when requeued.
+func (c *fakeAMQPConn) settle(ch *fakeAMQPChannel, deliveryTag uint64, ack, requeue bool) {
+	ch.mu.Lock()
+	d, ok := ch.deliveries[deliveryTag]
+	delete(ch.deliveries, deliveryTag)
+	ch.mu.Unlock()
+	if !ok {
+		return
+	}
+	s := c.server
+	s.mu.Lock()
+	s.settled = append(s.settled, fakeSettlement{Queue: d.queue, Body: string(d.msg.Body), Ack: ack, Requeue: requeue})
+	if requeue && d.get {
+		s.stored[d.queue] = append([]fakePublish{d.msg}, s.stored[d.queue]...)
+	}
+	s.mu.Unlock()
+	if requeue && !d.get {
+		s.deliver(d.queue, d.msg, true)
+	}
+}
+
+// requeueGets puts the messages fetched on ch and not settled back in their
+// queue, as the broker does when the channel closes.
+func (c *fakeAMQPConn) requeueGets(ch *fakeAMQPChannel) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Put back last fetched first, so the queue keeps its order.
+	for tag := ch.delivered; tag > 0; tag-- {
+		if d, ok := ch.deliveries[tag]; ok && d.get {
+			s.stored[d.queue] = append([]fakePublish{d.msg}, s.stored[d.queue]...)
+			delete(ch.deliveries, tag)
+		}
+	}
+}
+
+// track records d as delivered on ch and returns its delivery tag.
+func (ch *fakeAMQPChannel) track(d fakeDelivery) uint64 {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.delivered++
+	if ch.deliveries == nil {
+		ch.deliveries = map[uint64]fakeDelivery{}
+	}
+	ch.deliveries[ch.delivered] = d
+	return ch.delivered
+}
+
+func (c *fakeAMQPConn) sendMethod(id, class, method uint16, args *amqpWriter) {
+	payload := binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(nil, class), method)
+	c.send(1, id, append(payload, args.Bytes()...))
+}
+
+// sendContent sends the header frame, with props starting at the property
+// flags, and the body frame of a message.
+func (c *fakeAMQPConn) sendContent(id uint16, props, body []byte) {
+	header := new(amqpWriter).short(60).short(0).longlong(uint64(len(body)))
+	c.send(2, id, append(header.Bytes(), props...))
+	if len(body) > 0 {
+		c.send(3, id, body)
+	}
+}
+
+func (c *fakeAMQPConn) send(typ byte, id uint16, payload []byte) {
+	frame := new(amqpWriter).octet(typ).short(id).long(uint32(len(payload)))
+	frame.Write(payload)
+	frame.WriteByte(0xCE)
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.Write(frame.Bytes())
+}
+
+// parseProps reads the headers, correlation ID and reply address from the
+// basic properties, which start with the property flags.
+func (p *fakePublish) parseProps() {
+	in := &amqpReader{b: p.props}
+	flags := in.short()
+	if flags&0x8000 != 0 {
+		in.shortstr()
+	}
+	if flags&0x4000 != 0 {
+		in.shortstr()
+	}
+	if flags&0x2000 != 0 {
+		p.Headers = in.table()
+	}
+	if flags&0x1000 != 0 {
+		in.octet()
+	}
+	if flags&0x0800 != 0 {
+		in.octet()
+	}
+	if flags&0x0400 != 0 {
+		p.CorrelationId = in.shortstr()
+	}
+	if flags&0x0200 != 0 {
+		p.ReplyTo = in.shortstr()
+	}
+}
+
+// encodeProps encodes the headers, correlation ID and reply address of p as
+// basic properties, starting with the property flags.
+func (p fakePublish) encodeProps() []byte {
+	props := new(amqpWriter)
+	var flags uint16
+	if p.Headers != nil {
+		flags |= 0x2000
+		props.table(p.Headers)
+	}
+	if p.CorrelationId != "" {
+		flags |= 0x0400
+		props.shortstr(p.CorrelationId)
+	}
+	if p.ReplyTo != "" {
+		flags |= 0x0200
+		props.shortstr(p.ReplyTo)
+	}
+	return append(binary.BigEndian.AppendUint16(nil, flags), props.Bytes()...)
+}
+
+// amqpWriter encodes AMQP fields.
+type amqpWriter struct {
+	bytes.Buffer
+}
+
+func (w *amqpWriter) octet(v byte) *amqpWriter {
+	w.WriteByte(v)
+	return w
+}
+
+func (w *amqpWriter) short(v uint16) *amqpWriter {
+	binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *amqpWriter) long(v uint32) *amqpWriter {
+	binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *amqpWriter) longlong(v uint64) *amqpWriter {
+	binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *amqpWriter) shortstr(s string) *amqpWriter {
+	w.WriteByte(byte(len(s)))
+	w.WriteString(s)
+	return w
+}
+
+func (w *amqpWriter) longstr(s string) *amqpWriter {
+	w.long(uint32(len(s)))
+	w.WriteString(s)
+	return w
+}
+
+// table encodes a field table of string, int32 and int64 values.
+func (w *amqpWriter) table(fields map[string]any) *amqpWriter {
+	t := new(amqpWriter)
+	for k, v := range fields {
+		t.shortstr(k)
+		switch v := v.(type) {
+		case string:
+			t.octet('S').longstr(v)
+		case int32:
+			t.octet('I').long(uint32(v))
+		case int64:
+			t.octet('l').longlong(uint64(v))
+		default:
+			panic(fmt.Sprintf("unsupported field value %T", v))
+		}
+	}
+	w.long(uint32(t.Len()))
+	w.Write(t.Bytes())
+	return w
+}
+
+// amqpReader decodes AMQP fields.
+type amqpReader struct {
+	b []byte
+}
+
+func (r *amqpReader) octet() byte {
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *amqpReader) short() uint16 {
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *amqpReader) long() uint32 {
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *amqpReader) longlong() uint64 {
+	v := binary.BigEndian.Uint64(r.b)
+	r.b = r.b[8:]
+	return v
+}
+
+func (r *amqpReader) shortstr() string {
+	n := int(r.b[0])
+	v := string(r.b[1 : 1+n])
+	r.b = r.b[1+n:]
+	return v
+}
+
+// longstr decodes a long string.
+func (r *amqpReader) longstr() string {
+	n := r.long()
+	v := string(r.b[:n])
+	r.b = r.b[n:]
+	return v
+}
+
+// table decodes a field table of the value types the client sends: strings,
+// booleans, int32, int64 and nested tables.
+func (r *amqpReader) table() map[string]any {
+	n := r.long()
+	in := &amqpReader{b: r.b[:n]}
+	r.b = r.b[n:]
+	fields := map[string]any{}
+	for len(in.b) > 0 {
+		key := in.shortstr()
+		switch typ := in.octet(); typ {
+		case 'S':
+			fields[key] = in.longstr()
+		case 't':
+			fields[key] = in.octet() != 0
+		case 'I':
+			fields[key] = int32(in.long())
+		case 'l':
+			fields[key] = int64(in.longlong())
+		case 'F':
+			fields[key] = in.table()
+		default:
+			panic("unsupported field type " + string(typ))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeTx is an in-memory pgx.Tx recording what repositories run on it, bound
+// with repository.NewTx and InTx. Methods no test needs panic through the nil
+// embedded interface.
+type fakeTx struct {
+	pgx.Tx
+	children   []*fakeTx
+	committed  bool
+	rolledBack bool
+
+	execs   []string
+	execErr error
+	queries []string
+	rows    [][]any
+	copied  [][]any
+}
+
+func (tx *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	child := &fakeTx{execErr: tx.execErr, rows: tx.rows}
+	tx.children = append(tx.children, child)
+	return child, nil
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if !tx.committed {
+		tx.rolledBack = true
+	}
+	return nil
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx.execs = append(tx.execs, sql)
+	return pgconn.NewCommandTag("INSERT 0 1"), tx.execErr
+}
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx.queries = append(tx.queries, sql)
+	return &fakeRows{rows: tx.rows, i: -1}, nil
+}
+
+func (tx *fakeTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return 0, err
+		}
+		tx.copied = append(tx.copied, values)
+	}
+	return int64(len(tx.copied)), rowSrc.Err()
+}
+
+// fakeRows returns rows, scanning each value into the matching target.
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	i    int
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, value := range r.rows[r.i] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+func (r *fakeRows) Close()     {}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_SQL(t *testing.T) {
+	filter := repository.And(
+		repository.Gte("due_date", "2026-01-01"),
+		repository.Or(repository.In("status", "open", "late"), repository.IsNull("closed_at", true)),
+		repository.Between("grade", 4, 10),
+	)
+
+	sql, args, err := filter.SQL(0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `("due_date" >= $1) AND (("status" IN ($2, $3)) OR ("closed_at" IS NULL)) AND ("grade" BETWEEN $4 AND $5)`, sql)
+	assert.Equal(t, []any{"2026-01-01", "open", "late", 4, 10}, args)
+}
+
+func TestParseFilterQuery(t *testing.T) {
+	values, _ := url.ParseQuery("due_date[gte]=2026-01-01&status[in]=open,late&title=TP1&limit=10&page_size=3")
+
+	filter, err := repository.ParseFilterQuery(values, []string{"due_date", "status", "title"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, repository.And(
+		repository.Gte("due_date", "2026-01-01"),
+		repository.In("status", "open", "late"),
+		repository.Eq("title", "TP1"),
+	), filter)
+}
+
+func TestParseFilterQuery_Rejects(t *testing.T) {
+	for _, query := range []string{"secret[eq]=1", "status[regex]=x", "due_date[between]=2026-01-01", "closed_at[null]=maybe"} {
+		values, _ := url.ParseQuery(query)
+		_, err := repository.ParseFilterQuery(values, []string{"status", "due_date", "closed_at"})
+		assert.Error(t, err, query)
+	}
+}
+
+func TestStructParser_GetQueryFilter(t *testing.T) {
+	query, args, err := repository.NewStructParser[ForumPost]().GetQueryFilter(repository.ILike("body", "%tp%"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT "id", "body", "created_at", "updated_at", "updated_by", "deleted_at" FROM "forum_posts" WHERE ("body" ILIKE $1) AND "deleted_at" IS NULL`, query)
+	assert.Equal(t, []any{"%tp%"}, args)
+
+	_, _, err = repository.NewStructParser[ForumPost]().GetQueryFilter(repository.Eq("password", "x"))
+	assert.Error(t, err)
+}
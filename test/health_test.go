@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/health"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/notifications"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthReadiness_AllUp(t *testing.T) {
+	health.Register("test.cache", health.CheckerFunc(func(ctx context.Context) health.Check {
+		return health.Check{Status: health.StatusUp, Details: map[string]any{"entries": 3}}
+	}))
+	defer health.Unregister("test.cache")
+
+	r := setupGin()
+	health.RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/health/ready", nil))
+
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, health.StatusUp, report.Checks["test.cache"].Status)
+	assert.NotEmpty(t, report.Checks["test.cache"].Latency)
+}
+
+func TestHealthReadiness_OneDown(t *testing.T) {
+	health.Register("test.up", health.CheckerFunc(func(ctx context.Context) health.Check {
+		return health.Check{Status: health.StatusUp}
+	}))
+	health.Register("test.down", health.CheckerFunc(func(ctx context.Context) health.Check {
+		return health.Check{Status: health.StatusDown, Error: "unreachable"}
+	}))
+	defer health.Unregister("test.up")
+	defer health.Unregister("test.down")
+
+	r := setupGin()
+	health.RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/health/ready", nil))
+
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, "unreachable", report.Checks["test.down"].Error)
+}
+
+func TestHealthLiveness(t *testing.T) {
+	r := setupGin()
+	health.RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/health/live", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetRouter_HealthRoutesAreOptIn(t *testing.T) {
+	r := utils.GetRouter()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/health/live", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// A service with its own probes keeps them; the others mount ours.
+	assert.NotPanics(t, func() { r.GET("/health/ready", func(c *gin.Context) {}) })
+	other := utils.GetRouter()
+	health.RegisterRoutes(other)
+	for _, path := range []string{"/health/live", "/health/ready"} {
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.NotEqual(t, http.StatusNotFound, w.Code, path)
+	}
+}
+
+func TestRabbitmqHealthChecksAreOptIn(t *testing.T) {
+	previous := logger.Logger
+	defer func() { logger.Logger = previous }()
+	broker := rabbitmq.NewFakeBroker()
+	logs := broker.NewClient("courses", rabbitmq.Config{}, []string{logger.LogExchangeName, logger.StatsExchangeName})
+	emails := broker.NewClient("courses", rabbitmq.Config{}, []string{notifications.NotificationsExchangeName})
+	defer health.Unregister(logger.HealthCheckName)
+	defer health.Unregister(notifications.HealthCheckName)
+
+	assert.NoError(t, logger.InitLoggerWithClient("courses", logger.Error, os.Stdout, logs))
+	notifications.InitWithClient(emails)
+	registered := health.Run(context.Background()).Checks
+	assert.NotContains(t, registered, logger.HealthCheckName)
+	assert.NotContains(t, registered, notifications.HealthCheckName)
+
+	logger.RegisterHealthCheck()
+	notifications.RegisterHealthCheck()
+	registered = health.Run(context.Background()).Checks
+	assert.Equal(t, health.StatusUp, registered[logger.HealthCheckName].Status)
+	assert.Equal(t, health.StatusUp, registered[notifications.HealthCheckName].Status)
+}
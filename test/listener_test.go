@@ -0,0 +1,23 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListener_RunStopsWithContextWhileReconnecting(t *testing.T) {
+	config, err := pgx.ParseConfig("postgres://app@127.0.0.1:1/users?sslmode=disable")
+	assert.NoError(t, err)
+	listener := database.NewListenerWithConfig(config)
+	database.SubscribeJSON[map[string]any](listener, "grades_changed", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, listener.Run(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations_SortedWithDownFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_courses.up.sql":   {Data: []byte("CREATE TABLE courses (id TEXT);")},
+		"0002_add_courses.down.sql": {Data: []byte("DROP TABLE courses;")},
+		"0001_create_users.up.sql":  {Data: []byte("CREATE TABLE users (id TEXT);")},
+		"README.md":                 {Data: []byte("ignored")},
+	}
+
+	migrations, err := database.LoadMigrations(fsys)
+
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Empty(t, migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "DROP TABLE courses;", migrations[1].Down)
+	assert.Len(t, migrations[1].Checksum, 64)
+}
+
+func TestLoadMigrations_ChecksumTracksUpFile(t *testing.T) {
+	first, _ := database.LoadMigrations(fstest.MapFS{"0001_a.up.sql": {Data: []byte("SELECT 1;")}})
+	second, _ := database.LoadMigrations(fstest.MapFS{"0001_a.up.sql": {Data: []byte("SELECT 2;")}})
+
+	assert.NotEqual(t, first[0].Checksum, second[0].Checksum)
+}
+
+func TestLoadMigrations_MissingUpFile(t *testing.T) {
+	_, err := database.LoadMigrations(fstest.MapFS{"0001_a.down.sql": {Data: []byte("DROP TABLE a;")}})
+
+	assert.Error(t, err)
+}
+
+func TestLoadMigrations_DuplicateVersion(t *testing.T) {
+	_, err := database.LoadMigrations(fstest.MapFS{
+		"0001_a.up.sql": {Data: []byte("SELECT 1;")},
+		"0001_b.up.sql": {Data: []byte("SELECT 1;")},
+	})
+
+	assert.Error(t, err)
+}
+
+func TestRollback_RejectsNonPositiveCount(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		err := database.Rollback(context.Background(), fstest.MapFS{}, n)
+
+		assert.Error(t, err, n)
+	}
+}
+
+func TestRunMigrations_ReturnsMigrateError(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "0001_a.up.sql"), []byte("SELECT 1;"), 0o644)
+	os.WriteFile(filepath.Join(dir, "0001_b.up.sql"), []byte("SELECT 1;"), 0o644)
+	t.Setenv("MIGRATION_DIR", dir)
+
+	assert.Error(t, database.RunMigrations())
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePageRequest_Defaults(t *testing.T) {
+	page, err := repository.ParsePageRequest(url.Values{}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, repository.DefaultPageLimit, page.Limit)
+	assert.Equal(t, 0, page.Offset)
+	assert.Empty(t, page.OrderBy)
+}
+
+func TestParsePageRequest_SortAndLimit(t *testing.T) {
+	values := url.Values{"limit": {"500"}, "offset": {"40"}, "sort": {"-due_date,id"}}
+
+	page, err := repository.ParsePageRequest(values, []string{"due_date", "id"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, repository.MaxPageLimit, page.Limit)
+	assert.Equal(t, 40, page.Offset)
+	assert.Equal(t, []repository.OrderField{{Field: "due_date", Desc: true}, {Field: "id"}}, page.OrderBy)
+}
+
+func TestParsePageRequest_UnknownSortField(t *testing.T) {
+	_, err := repository.ParsePageRequest(url.Values{"sort": {"password"}}, []string{"id"})
+
+	assert.Error(t, err)
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor, err := repository.EncodeCursor("2026-01-01", "abc")
+	assert.NoError(t, err)
+
+	values, err := repository.DecodeCursor(cursor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"2026-01-01", "abc"}, values)
+}
+
+func TestCursor_LargeIntegersAreNotRounded(t *testing.T) {
+	cursor, err := repository.EncodeCursor(int64(9007199254740993), 2.5)
+	assert.NoError(t, err)
+
+	values, err := repository.DecodeCursor(cursor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int64(9007199254740993), 2.5}, values)
+}
+
+func TestKeysetCondition(t *testing.T) {
+	order := []repository.OrderField{{Field: "due_date"}, {Field: "id", Desc: true}}
+
+	cond, args, err := repository.KeysetCondition(order, []any{"2026-01-01", 7}, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `("due_date" > $3) OR ("due_date" = $3 AND "id" < $4)`, cond)
+	assert.Equal(t, []any{"2026-01-01", 7}, args)
+	assert.Equal(t, `ORDER BY "due_date" ASC, "id" DESC`, repository.OrderByClause(order))
+}
+
+func TestParsePageRequest_InvalidCursor(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "W10", "WyJhIl14"} {
+		_, err := repository.ParsePageRequest(url.Values{"cursor": {cursor}}, nil)
+
+		assert.ErrorContains(t, err, "invalid cursor", cursor)
+	}
+}
+
+func TestGetPage_InvalidCursorIsBadRequest(t *testing.T) {
+	tx := &fakeTx{}
+	repo := repository.NewRepository(repository.NewStructParser[ForumPost]())
+	posts := repo.InTx(repository.NewTx(tx))
+	// A cursor issued for ?sort=body,id has one value too many for the
+	// default order by id.
+	cursor, _ := repository.EncodeCursor("hello", "p1")
+
+	tampered := posts.GetPageCtx(context.Background(), nil, repository.PageRequest{Cursor: "garbage!"})
+	mismatched := posts.GetPageCtx(context.Background(), nil, repository.PageRequest{Cursor: cursor})
+
+	assert.Equal(t, 400, tampered.GetStatus())
+	assert.Equal(t, 400, mismatched.GetStatus())
+	assert.Empty(t, tx.queries)
+}
@@ -2,18 +2,12 @@
 package test
 
 import (
-	"os"
 	"testing"
 
-	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
 	"github.com/Class-Connect-GRUPO-5/microservices-common/utils"
 	"github.com/stretchr/testify/assert"
 )
 
-func init() {
-	logger.InitLogger("password_test", logger.Error, os.Stdout, true)
-}
-
 func TestHashPassword_Success(t *testing.T) {
 	password := "MySecurePassword123!"
 
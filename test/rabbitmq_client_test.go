@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/health"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientContext_GivesUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	client, err := rabbitmq.NewClientContext(ctx, "test", rabbitmq.Config{Host: "127.0.0.1", Port: 1}, []string{"logs"})
+
+	assert.Nil(t, client)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_NilIsDisabled(t *testing.T) {
+	var client *rabbitmq.Client
+
+	assert.NoError(t, client.Send("logs", nil, []byte("hi")))
+	assert.Equal(t, 0, client.Pending())
+	assert.Equal(t, health.StatusDown, client.CheckHealth(context.Background()).Status)
+	assert.False(t, client.Blocked())
+	assert.NoError(t, client.Shutdown(context.Background()))
+}
+
+func TestPermanent(t *testing.T) {
+	decodeErr := errors.New("invalid json")
+
+	err := fmt.Errorf("handling notification: %w", rabbitmq.Permanent(decodeErr))
+
+	assert.True(t, rabbitmq.IsPermanent(err))
+	assert.ErrorIs(t, err, decodeErr)
+	assert.False(t, rabbitmq.IsPermanent(decodeErr))
+}
+
+func TestRetryNamingAndAttempts(t *testing.T) {
+	assert.Equal(t, "emails.retry.30s", rabbitmq.RetryQueue("emails", 30*time.Second))
+	assert.Equal(t, "emails.dead", rabbitmq.DeadLetterQueue("emails"))
+	assert.Equal(t, "emails.dlx", rabbitmq.DeadLetterExchange("emails"))
+
+	assert.Equal(t, 0, rabbitmq.Attempts(amqp.Delivery{}))
+	assert.Equal(t, 2, rabbitmq.Attempts(amqp.Delivery{Headers: amqp.Table{"attempts": int32(2), "source": "users"}}))
+}
+
+func TestNewClientContext_LogsEveryRetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	var logs []string
+
+	_, err := rabbitmq.NewClientContext(ctx, "test", rabbitmq.Config{Host: "127.0.0.1", Port: 1, Logf: func(format string, args ...any) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}}, []string{"logs"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	if assert.NotEmpty(t, logs) {
+		assert.Contains(t, logs[0], "attempt 1 failed")
+		assert.Contains(t, logs[0], "connection refused")
+	}
+}
+
+func TestNewClientContext_FailsWhenDeclarationIsRefused(t *testing.T) {
+	s := newFakeAMQP(t)
+	s.refuse = map[string]uint16{"logs": amqp.PreconditionFailed}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	client, err := rabbitmq.NewClientContext(ctx, "test", s.config(rabbitmq.Config{}), []string{"logs"})
+
+	var amqpErr *amqp.Error
+	assert.Nil(t, client)
+	assert.ErrorAs(t, err, &amqpErr)
+	assert.Equal(t, amqp.PreconditionFailed, amqpErr.Code)
+	assert.NoError(t, ctx.Err(), "a refusal is not retried")
+}
+
+func TestClient_ReconnectsAndRedeclaresExchanges(t *testing.T) {
+	s := newFakeAMQP(t)
+	client := s.client(t, rabbitmq.Config{Exchanges: []rabbitmq.Exchange{
+		{Name: "logs", Kind: amqp.ExchangeTopic, Durable: true},
+	}, Logf: func(string, ...any) {}})
+	assert.Len(t, s.declared(), 1)
+
+	s.disconnect()
+
+	assert.Eventually(t, func() bool {
+		return len(s.declared()) == 2 && client.State() == rabbitmq.StateConnected
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, s.declared()[0], s.declared()[1])
+	assert.NoError(t, client.SendWithKey("logs", "logs.error", nil, []byte("after restart")))
+	assert.Equal(t, "after restart", string((<-s.received).Body))
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLogs collects what a client reports to Config.Logf.
+type fakeLogs struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *fakeLogs) logf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *fakeLogs) all() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.lines...)
+}
+
+// consume runs client.Consume on the "tasks.grading" queue of the "tasks"
+// exchange until the test ends, and waits for the consumer to be registered.
+func consume(t *testing.T, s *fakeAMQP, client *rabbitmq.Client, handler rabbitmq.Handler, options rabbitmq.ConsumerOptions) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.Consume(ctx, "tasks", "tasks.grading", handler, options) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_, ok := s.consumers["tasks.grading"]
+		return ok
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestConsume_RequeuesOnceThenDropsWithoutRetry(t *testing.T) {
+	s := newFakeAMQP(t)
+	logs := &fakeLogs{}
+	client := s.client(t, rabbitmq.Config{Logf: logs.logf})
+	var calls atomic.Int32
+	consume(t, s, client, func(ctx context.Context, d amqp.Delivery) error {
+		calls.Add(1)
+		return errors.New("grader unavailable")
+	}, rabbitmq.ConsumerOptions{})
+
+	s.deliver("tasks.grading", fakePublish{Exchange: "tasks", Body: []byte("task 1")}, false)
+
+	assert.Eventually(t, func() bool { return len(s.settlements()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []fakeSettlement{
+		{Queue: "tasks.grading", Body: "task 1", Requeue: true},
+		{Queue: "tasks.grading", Body: "task 1"},
+	}, s.settlements())
+	assert.Equal(t, int32(2), calls.Load())
+	if assert.Len(t, logs.all(), 1) {
+		assert.Contains(t, logs.all()[0], "dropping message")
+		assert.Contains(t, logs.all()[0], "grader unavailable")
+	}
+}
+
+func TestConsume_RedeliveredByTheBrokerIsDroppedOnFirstFailure(t *testing.T) {
+	s := newFakeAMQP(t)
+	logs := &fakeLogs{}
+	client := s.client(t, rabbitmq.Config{Logf: logs.logf})
+	consume(t, s, client, func(ctx context.Context, d amqp.Delivery) error {
+		return errors.New("grader unavailable")
+	}, rabbitmq.ConsumerOptions{})
+
+	// In flight when the previous consumer went away, never handled.
+	s.deliver("tasks.grading", fakePublish{Exchange: "tasks", Body: []byte("task 1")}, true)
+
+	assert.Eventually(t, func() bool { return len(s.settlements()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.False(t, s.settlements()[0].Requeue)
+	assert.Len(t, logs.all(), 1, "the drop is logged")
+}
+
+func TestConsume_AcksAndDeadLettersPermanentErrors(t *testing.T) {
+	s := newFakeAMQP(t)
+	client := s.client(t, rabbitmq.Config{Logf: func(string, ...any) {}})
+	consume(t, s, client, func(ctx context.Context, d amqp.Delivery) error {
+		if string(d.Body) == "bad" {
+			return rabbitmq.Permanent(errors.New("invalid json"))
+		}
+		return nil
+	}, rabbitmq.ConsumerOptions{DeadLetter: true})
+
+	s.deliver("tasks.grading", fakePublish{Exchange: "tasks", Key: "grade", Body: []byte("ok")}, false)
+	s.deliver("tasks.grading", fakePublish{Exchange: "tasks", Key: "grade", Body: []byte("bad")}, false)
+
+	dead := <-s.received
+	assert.Eventually(t, func() bool { return len(s.settlements()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []fakeSettlement{
+		{Queue: "tasks.grading", Body: "ok", Ack: true},
+		{Queue: "tasks.grading", Body: "bad", Ack: true},
+	}, s.settlements(), "the dead lettered copy is confirmed before the original is acked")
+	assert.Equal(t, rabbitmq.DeadLetterExchange("tasks.grading"), dead.Exchange)
+	assert.Equal(t, "bad", string(dead.Body))
+	assert.Equal(t, int32(1), dead.Headers[rabbitmq.AttemptsHeader])
+	assert.Equal(t, "invalid json", dead.Headers[rabbitmq.LastErrorHeader])
+	assert.Equal(t, "tasks", dead.Headers[rabbitmq.OriginalExchangeHeader])
+	assert.Equal(t, "grade", dead.Headers[rabbitmq.OriginalRoutingKeyHeader])
+}
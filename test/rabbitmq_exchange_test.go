@@ -0,0 +1,116 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger/events/user_events"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_DeclaresExchangeOptions(t *testing.T) {
+	s := newFakeAMQP(t)
+
+	s.client(t, rabbitmq.Config{Exchanges: []rabbitmq.Exchange{
+		{Name: logger.LogExchangeName, Kind: amqp.ExchangeTopic, Durable: true, Args: amqp.Table{"alternate-exchange": "unrouted"}},
+		{Name: "sessions", AutoDelete: true},
+	}})
+
+	assert.Equal(t, []fakeExchange{
+		{Name: logger.LogExchangeName, Kind: amqp.ExchangeTopic, Durable: true, Args: map[string]any{"alternate-exchange": "unrouted"}},
+		{Name: "sessions", Kind: amqp.ExchangeFanout, AutoDelete: true, Args: map[string]any{}},
+	}, s.declared())
+}
+
+func TestClient_ConsumeDefaultBindingKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange string
+		keys     []string
+		want     []fakeBinding
+	}{
+		{"topic binds every key", logger.LogExchangeName, nil, []fakeBinding{{"audit", logger.LogExchangeName, "#"}}},
+		{"fanout binds the empty key", "grades", nil, []fakeBinding{{"audit", "grades", ""}}},
+		{"explicit keys", logger.LogExchangeName, []string{"logs.error", "logs.warn"}, []fakeBinding{
+			{"audit", logger.LogExchangeName, "logs.error"},
+			{"audit", logger.LogExchangeName, "logs.warn"},
+		}},
+		{"default exchange is not bound", "", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeAMQP(t)
+			client := s.client(t, rabbitmq.Config{Exchanges: []rabbitmq.Exchange{
+				{Name: logger.LogExchangeName, Kind: amqp.ExchangeTopic},
+			}})
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- client.Consume(ctx, tt.exchange, "audit", func(ctx context.Context, d amqp.Delivery) error {
+					return nil
+				}, rabbitmq.ConsumerOptions{BindingKeys: tt.keys})
+			}()
+
+			// Wait a little longer so unexpected extra bindings are recorded.
+			assert.Eventually(t, func() bool { return len(s.bound()) == len(tt.want) }, time.Second, 5*time.Millisecond)
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+
+			assert.NoError(t, <-done)
+			if tt.want == nil {
+				assert.Empty(t, s.bound())
+			} else {
+				assert.Equal(t, tt.want, s.bound())
+			}
+			if tt.exchange == "grades" {
+				assert.Contains(t, s.declared(), fakeExchange{Name: "grades", Kind: amqp.ExchangeFanout, Args: map[string]any{}},
+					"exchanges without options are declared as non-durable fanout")
+			}
+		})
+	}
+}
+
+func TestClient_SendWithKey(t *testing.T) {
+	s := newFakeAMQP(t)
+	client := s.client(t, rabbitmq.Config{Exchanges: []rabbitmq.Exchange{
+		{Name: logger.LogExchangeName, Kind: amqp.ExchangeTopic},
+	}})
+
+	assert.NoError(t, client.SendWithKey(logger.LogExchangeName, "logs.error", nil, []byte("boom")))
+	assert.NoError(t, client.Send(logger.LogExchangeName, nil, []byte("hi")))
+
+	first, second := <-s.received, <-s.received
+	source := map[string]any{"source": "courses"}
+	assert.Equal(t, fakePublish{Exchange: logger.LogExchangeName, Key: "logs.error", Headers: source, Body: []byte("boom")}, strip(first))
+	assert.Equal(t, fakePublish{Exchange: logger.LogExchangeName, Key: "", Headers: source, Body: []byte("hi")}, strip(second))
+}
+
+func TestLogger_RoutingKeys(t *testing.T) {
+	previous := logger.Logger
+	defer func() { logger.Logger = previous }()
+	s := newFakeAMQP(t)
+	client := s.client(t, rabbitmq.Config{})
+	var output bytes.Buffer
+
+	assert.NoError(t, logger.InitLoggerWithClient("courses", logger.Debug, &output, client))
+	logger.Logger.Warn("slow query")
+	logger.Logger.Emit(&user_events.UserBanned{UserID: "42"})
+
+	warn, stat := <-s.received, <-s.received
+	assert.Equal(t, logger.LogExchangeName, warn.Exchange)
+	assert.Equal(t, "logs.warn", warn.Key)
+	assert.Equal(t, logger.StatsExchangeName, stat.Exchange)
+	assert.Equal(t, "stats.UserBanned", stat.Key)
+	assert.JSONEq(t, `{"user_id":"42"}`, string(stat.Body))
+}
+
+// strip drops the encoded properties of p, keeping the fields tests compare.
+func strip(p fakePublish) fakePublish {
+	p.props, p.size = nil, 0
+	return p
+}
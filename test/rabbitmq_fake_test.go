@@ -0,0 +1,198 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/notifications"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/notifications/notification_types"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeBroker_Notifications(t *testing.T) {
+	broker := rabbitmq.NewFakeBroker()
+	notifications.InitWithClient(broker.NewClient("courses", rabbitmq.Config{}, []string{notifications.NotificationsExchangeName}))
+	assert.NoError(t, broker.Bind("emails", notifications.NotificationsExchangeName, ""))
+
+	err := notifications.Send("42", &notification_types.WelcomeNotification{Name: "Ada"})
+
+	assert.NoError(t, err)
+	published := broker.PublishedTo(notifications.NotificationsExchangeName)
+	assert.Len(t, published, 1)
+	assert.Equal(t, "notification.Welcome", published[0].RoutingKey)
+	assert.Equal(t, "42", published[0].Headers["user"])
+	messages := broker.Messages("emails")
+	assert.Len(t, messages, 1)
+	assert.JSONEq(t, `{"name":"Ada"}`, string(messages[0].Body))
+}
+
+func TestFakeBroker_TopicRouting(t *testing.T) {
+	broker := rabbitmq.NewFakeBroker()
+	client := broker.NewClient("courses", rabbitmq.Config{Exchanges: []rabbitmq.Exchange{
+		{Name: logger.LogExchangeName, Kind: amqp.ExchangeTopic},
+	}}, []string{logger.LogExchangeName, logger.StatsExchangeName})
+	assert.NoError(t, broker.Bind("errors", logger.LogExchangeName, "logs.error"))
+	assert.NoError(t, broker.Bind("all", logger.LogExchangeName, "logs.#"))
+
+	assert.NoError(t, client.SendWithKey(logger.LogExchangeName, "logs.error", nil, []byte("boom")))
+	assert.NoError(t, client.SendWithKey(logger.LogExchangeName, "logs.info", nil, []byte("hi")))
+
+	assert.Len(t, broker.Messages("errors"), 1)
+	assert.Len(t, broker.Messages("all"), 2)
+	assert.Error(t, client.Send("missing", nil, nil))
+}
+
+func TestFakeBroker_LoggerWithClient(t *testing.T) {
+	previous := logger.Logger
+	defer func() { logger.Logger = previous }()
+	broker := rabbitmq.NewFakeBroker()
+	client := broker.NewClient("courses", rabbitmq.Config{}, []string{logger.LogExchangeName, logger.StatsExchangeName})
+
+	assert.NoError(t, logger.InitLoggerWithClient("courses", logger.Error, os.Stdout, client))
+	logger.Logger.Error("boom")
+
+	published := broker.PublishedTo(logger.LogExchangeName)
+	assert.Len(t, published, 1)
+	assert.Equal(t, "logs.error", published[0].RoutingKey)
+	assert.Equal(t, "courses", published[0].Headers["source"])
+}
+
+func TestFakeBroker_ConsumeRetriesThenDeadLetters(t *testing.T) {
+	broker := rabbitmq.NewFakeBroker()
+	client := broker.NewClient("courses", rabbitmq.Config{}, []string{"tasks"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.NoError(t, broker.Bind("tasks.grading", "tasks", ""))
+
+	var calls atomic.Int32
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Consume(ctx, "tasks", "tasks.grading", func(ctx context.Context, d amqp.Delivery) error {
+			calls.Add(1)
+			return errors.New("grader unavailable")
+		}, rabbitmq.ConsumerOptions{Retry: &rabbitmq.RetryPolicy{MaxAttempts: 3}})
+	}()
+
+	assert.NoError(t, client.Send("tasks", nil, []byte("task 1")))
+	var dead []amqp.Delivery
+	assert.Eventually(t, func() bool {
+		dead = append(dead, broker.Messages(rabbitmq.DeadLetterQueue("tasks.grading"))...)
+		return len(dead) == 1
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, 3, rabbitmq.Attempts(dead[0]))
+	assert.Equal(t, "grader unavailable", dead[0].Headers[rabbitmq.LastErrorHeader])
+}
+
+func TestFakeBroker_RPC(t *testing.T) {
+	broker := rabbitmq.NewFakeBroker()
+	server := broker.NewClient("users", rabbitmq.Config{}, nil)
+	caller := broker.NewClient("courses", rabbitmq.Config{}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := rabbitmq.CallJSON[displayNameRequest, string](ctx, caller, "users.display_name", displayNameRequest{UserID: "42"})
+	assert.ErrorIs(t, err, rabbitmq.ErrNoResponder)
+
+	broker.DeclareQueue("users.display_name")
+	go server.Serve(ctx, "users.display_name", rabbitmq.HandleJSON(func(ctx context.Context, req displayNameRequest) (string, error) {
+		if req.UserID == "0" {
+			return "", errors.New("user not found")
+		}
+		return "Ada " + req.UserID, nil
+	}), rabbitmq.ConsumerOptions{})
+
+	name, err := rabbitmq.CallJSON[displayNameRequest, string](ctx, caller, "users.display_name", displayNameRequest{UserID: "42"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada 42", name)
+
+	_, err = rabbitmq.CallJSON[displayNameRequest, string](ctx, caller, "users.display_name", displayNameRequest{UserID: "0"})
+	var remote *rabbitmq.RemoteError
+	assert.ErrorAs(t, err, &remote)
+	assert.Equal(t, "user not found", remote.Message)
+}
+
+func TestFakeBroker_ReplayDeadLettersOnlyToFailedQueue(t *testing.T) {
+	broker := rabbitmq.NewFakeBroker()
+	client := broker.NewClient("courses", rabbitmq.Config{}, []string{"grades"})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	assert.NoError(t, broker.Bind("grades.audit", "grades", ""))
+	assert.NoError(t, broker.Bind("grades.email", "grades", ""))
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Consume(ctx, "grades", "grades.email", func(ctx context.Context, d amqp.Delivery) error {
+			return rabbitmq.Permanent(errors.New("smtp down"))
+		}, rabbitmq.ConsumerOptions{DeadLetter: true})
+	}()
+
+	assert.NoError(t, client.Send("grades", nil, []byte("grade 1")))
+	assert.Eventually(t, func() bool {
+		dead, _ := client.DeadLetters("grades.email", 10)
+		return len(dead) == 1
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+
+	dead, err := client.DeadLetters("grades.email", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "grades", dead[0].OriginalExchange)
+	assert.Len(t, broker.Messages("grades.audit"), 1)
+
+	replayed, err := client.ReplayDeadLetters(context.Background(), "grades.email", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Empty(t, broker.Messages("grades.audit"), "the other queues of the fanout exchange are not sent the message again")
+	retried := broker.Messages("grades.email")
+	assert.Len(t, retried, 1)
+	assert.Equal(t, "grade 1", string(retried[0].Body))
+	assert.Equal(t, 0, rabbitmq.Attempts(retried[0]))
+	assert.Nil(t, retried[0].Headers[rabbitmq.LastErrorHeader])
+	assert.Empty(t, broker.Messages(rabbitmq.DeadLetterQueue("grades.email")))
+}
+
+func TestFakeBroker_ConsumeRequeuesOnceWithoutRetry(t *testing.T) {
+	for _, deadLetter := range []bool{false, true} {
+		broker := rabbitmq.NewFakeBroker()
+		client := broker.NewClient("courses", rabbitmq.Config{}, []string{"tasks"})
+		ctx, cancel := context.WithCancel(context.Background())
+		assert.NoError(t, broker.Bind("tasks.grading", "tasks", ""))
+
+		var calls atomic.Int32
+		done := make(chan error, 1)
+		go func() {
+			done <- client.Consume(ctx, "tasks", "tasks.grading", func(ctx context.Context, d amqp.Delivery) error {
+				calls.Add(1)
+				return errors.New("grader unavailable")
+			}, rabbitmq.ConsumerOptions{DeadLetter: deadLetter})
+		}()
+
+		assert.NoError(t, client.Send("tasks", nil, []byte("task 1")))
+		assert.Eventually(t, func() bool { return calls.Load() >= 2 }, time.Second, time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		assert.NoError(t, <-done)
+		assert.Equal(t, int32(2), calls.Load(), "deadLetter=%v", deadLetter)
+		assert.Empty(t, broker.Messages("tasks.grading"))
+		dead, _ := client.DeadLetters("tasks.grading", 10)
+		if deadLetter {
+			assert.Len(t, dead, 1)
+			assert.Equal(t, "grader unavailable", dead[0].LastError)
+		} else {
+			assert.Empty(t, dead)
+		}
+	}
+}
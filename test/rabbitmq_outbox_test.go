@@ -0,0 +1,50 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox_KeepsOrderAfterNack(t *testing.T) {
+	server := newFakeAMQP(t)
+	var once sync.Once
+	server.ack = func(p fakePublish) bool {
+		nacked := false
+		once.Do(func() { nacked = true })
+		return !nacked
+	}
+	client := server.client(t, rabbitmq.Config{Confirm: true, OutboxSize: 10})
+
+	for _, body := range []string{"1", "2", "3"} {
+		assert.NoError(t, client.Send("grades", nil, []byte(body)))
+	}
+
+	assert.Eventually(t, func() bool { return client.Pending() == 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"1", "1", "2", "3"}, server.bodies())
+}
+
+func TestOutbox_DropsPoisonMessage(t *testing.T) {
+	server := newFakeAMQP(t)
+	server.ack = func(p fakePublish) bool { return string(p.Body) != "poison" }
+	client := server.client(t, rabbitmq.Config{Confirm: true, OutboxSize: 10, OutboxMaxAttempts: 3})
+	dropped := make(chan rabbitmq.DroppedMessage, 1)
+	client.OnDrop(func(d rabbitmq.DroppedMessage) { dropped <- d })
+
+	assert.NoError(t, client.Send("grades", nil, []byte("poison")))
+	assert.NoError(t, client.Send("grades", nil, []byte("after")))
+
+	select {
+	case d := <-dropped:
+		assert.Equal(t, "grades", d.Exchange)
+		assert.Equal(t, "poison", string(d.Publishing.Body))
+		assert.ErrorIs(t, d.Err, rabbitmq.ErrNacked)
+	case <-time.After(3 * time.Second):
+		t.Fatal("poison message was not dropped")
+	}
+	assert.Eventually(t, func() bool { return client.Pending() == 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"poison", "poison", "poison", "after"}, server.bodies())
+}
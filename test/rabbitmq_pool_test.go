@@ -0,0 +1,126 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool_BoundsConcurrentPublishes(t *testing.T) {
+	server := newFakeAMQP(t)
+	server.hold = make(chan struct{})
+	client := server.client(t, rabbitmq.Config{Confirm: true, Channels: 2})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.Send("grades", nil, []byte("grade"))
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-server.received:
+		case <-time.After(time.Second):
+			t.Fatal("publishes did not start")
+		}
+	}
+	select {
+	case <-server.received:
+		t.Fatal("more publishes in flight than channels")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(server.hold)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Len(t, server.bodies(), 5)
+}
+
+func TestPool_ErrBlockedAfterTimeout(t *testing.T) {
+	server := newFakeAMQP(t)
+	client := server.client(t, rabbitmq.Config{BlockedTimeout: 100 * time.Millisecond})
+
+	server.block(true)
+	assert.Eventually(t, client.Blocked, time.Second, 5*time.Millisecond)
+	start := time.Now()
+	err := client.Send("grades", nil, []byte("grade"))
+	_, callErr := client.Call(context.Background(), "users.display_name", nil, nil)
+
+	assert.ErrorIs(t, err, rabbitmq.ErrBlocked)
+	assert.ErrorIs(t, callErr, rabbitmq.ErrBlocked)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.Empty(t, server.bodies())
+
+	server.block(false)
+	assert.Eventually(t, func() bool { return !client.Blocked() }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, client.Send("grades", nil, []byte("grade")))
+}
+
+func TestClient_ShutdownWaitsForPublishesInFlight(t *testing.T) {
+	server := newFakeAMQP(t)
+	server.hold = make(chan struct{})
+	client := server.client(t, rabbitmq.Config{Confirm: true})
+
+	sent := make(chan error, 1)
+	go func() { sent <- client.Send("grades", nil, []byte("grade")) }()
+	<-server.received
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- client.Shutdown(context.Background()) }()
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before the publish was confirmed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(server.hold)
+
+	assert.NoError(t, <-sent)
+	assert.NoError(t, <-shutdown)
+	assert.ErrorIs(t, client.Send("grades", nil, nil), rabbitmq.ErrNotConnected)
+}
+
+func TestClient_ShutdownFlushesOutbox(t *testing.T) {
+	server := newFakeAMQP(t)
+	var accepting atomic.Bool
+	server.ack = func(p fakePublish) bool { return accepting.Load() }
+	client := server.client(t, rabbitmq.Config{Confirm: true, OutboxSize: 10, OutboxMaxAttempts: 100})
+
+	assert.NoError(t, client.Send("grades", nil, []byte("grade")))
+	assert.Equal(t, 1, client.Pending())
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- client.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+	accepting.Store(true)
+
+	select {
+	case err := <-shutdown:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Shutdown did not return")
+	}
+	assert.Equal(t, 0, client.Pending())
+}
+
+func TestClient_ShutdownReportsUnflushedOutbox(t *testing.T) {
+	server := newFakeAMQP(t)
+	server.ack = func(p fakePublish) bool { return false }
+	client := server.client(t, rabbitmq.Config{Confirm: true, OutboxSize: 10, OutboxMaxAttempts: 100})
+	assert.NoError(t, client.Send("grades", nil, []byte("grade")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorContains(t, client.Shutdown(ctx), "1 messages left in the outbox")
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsume_DeclaresRetryTopology(t *testing.T) {
+	s := newFakeAMQP(t)
+	client := s.client(t, rabbitmq.Config{})
+	consume(t, s, client, func(ctx context.Context, d amqp.Delivery) error {
+		return nil
+	}, rabbitmq.ConsumerOptions{Durable: true, Retry: &rabbitmq.RetryPolicy{Delays: []time.Duration{5 * time.Second, 30 * time.Second}}})
+
+	assert.Equal(t, []fakeQueue{
+		{Name: rabbitmq.DeadLetterQueue("tasks.grading"), Durable: true, Args: map[string]any{}},
+		{Name: rabbitmq.RetryQueue("tasks.grading", 5*time.Second), Durable: true, Args: map[string]any{
+			"x-message-ttl":             int64(5000),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": "tasks.grading",
+		}},
+		{Name: rabbitmq.RetryQueue("tasks.grading", 30*time.Second), Durable: true, Args: map[string]any{
+			"x-message-ttl":             int64(30000),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": "tasks.grading",
+		}},
+		{Name: "tasks.grading", Durable: true, Args: map[string]any{"x-dead-letter-exchange": rabbitmq.DeadLetterExchange("tasks.grading")}},
+	}, s.declaredQueues())
+	assert.Contains(t, s.declared(), fakeExchange{Name: rabbitmq.DeadLetterExchange("tasks.grading"), Kind: amqp.ExchangeFanout, Durable: true, Args: map[string]any{}})
+	assert.Contains(t, s.bound(), fakeBinding{Queue: rabbitmq.DeadLetterQueue("tasks.grading"), Exchange: rabbitmq.DeadLetterExchange("tasks.grading")})
+}
+
+func TestConsume_RetryCountsAttemptsThenDeadLetters(t *testing.T) {
+	s := newFakeAMQP(t)
+	client := s.client(t, rabbitmq.Config{})
+	consume(t, s, client, func(ctx context.Context, d amqp.Delivery) error {
+		return errors.New("grader unavailable")
+	}, rabbitmq.ConsumerOptions{Retry: &rabbitmq.RetryPolicy{Delays: []time.Duration{5 * time.Second}, MaxAttempts: 3}})
+
+	var published []fakePublish
+	for attempt, headers := range []map[string]any{
+		{"source": "courses"},
+		{"source": "courses", rabbitmq.AttemptsHeader: int32(1)},
+		{"source": "courses", rabbitmq.AttemptsHeader: int32(2)},
+	} {
+		s.deliver("tasks.grading", fakePublish{Exchange: "tasks", Headers: headers, Body: []byte("task 1")}, attempt > 0)
+		published = append(published, <-s.received)
+	}
+
+	retryQueue := rabbitmq.RetryQueue("tasks.grading", 5*time.Second)
+	for i, p := range published[:2] {
+		assert.Equal(t, "", p.Exchange)
+		assert.Equal(t, retryQueue, p.Key)
+		assert.Equal(t, int32(i+1), p.Headers[rabbitmq.AttemptsHeader])
+	}
+	dead := published[2]
+	assert.Equal(t, rabbitmq.DeadLetterExchange("tasks.grading"), dead.Exchange)
+	assert.Equal(t, int32(3), dead.Headers[rabbitmq.AttemptsHeader])
+	assert.Equal(t, "grader unavailable", dead.Headers[rabbitmq.LastErrorHeader])
+	assert.Equal(t, "tasks", dead.Headers[rabbitmq.OriginalExchangeHeader])
+	assert.Equal(t, "courses", dead.Headers["source"])
+	assert.Eventually(t, func() bool { return len(s.settlements()) == 3 }, time.Second, 5*time.Millisecond)
+	for _, settlement := range s.settlements() {
+		assert.True(t, settlement.Ack, "each delivery is acked once its copy is confirmed")
+	}
+}
+
+func TestClient_DeadLettersAndReplay(t *testing.T) {
+	s := newFakeAMQP(t)
+	client := s.client(t, rabbitmq.Config{})
+	dlq := rabbitmq.DeadLetterQueue("tasks.grading")
+	for _, body := range []string{"task 1", "task 2"} {
+		s.store(dlq, fakePublish{Exchange: rabbitmq.DeadLetterExchange("tasks.grading"), Headers: map[string]any{
+			"user":                            "42",
+			rabbitmq.AttemptsHeader:           int32(3),
+			rabbitmq.LastErrorHeader:          "grader unavailable",
+			rabbitmq.OriginalExchangeHeader:   "tasks",
+			rabbitmq.OriginalRoutingKeyHeader: "grade",
+		}, Body: []byte(body)})
+	}
+
+	dead, err := client.DeadLetters("tasks.grading", 10)
+
+	assert.NoError(t, err)
+	if assert.Len(t, dead, 2) {
+		assert.Equal(t, "task 1", string(dead[0].Delivery.Body))
+		assert.Equal(t, 3, dead[0].Attempts)
+		assert.Equal(t, "grader unavailable", dead[0].LastError)
+		assert.Equal(t, "tasks", dead[0].OriginalExchange)
+		assert.Equal(t, "grade", dead[0].OriginalRoutingKey)
+	}
+	assert.Len(t, s.storedIn(dlq), 2, "inspecting dead letters leaves them in the queue")
+
+	replayed, err := client.ReplayDeadLetters(context.Background(), "tasks.grading", 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	p := <-s.received
+	assert.Equal(t, "", p.Exchange, "replayed through the default exchange")
+	assert.Equal(t, "tasks.grading", p.Key)
+	assert.Equal(t, "task 1", string(p.Body))
+	assert.Equal(t, map[string]any{"user": "42"}, p.Headers, "the failure headers are reset")
+	remaining := s.storedIn(dlq)
+	if assert.Len(t, remaining, 1) {
+		assert.Equal(t, "task 2", string(remaining[0].Body))
+	}
+	assert.Contains(t, s.settlements(), fakeSettlement{Queue: dlq, Body: "task 1", Ack: true})
+}
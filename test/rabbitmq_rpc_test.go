@@ -0,0 +1,126 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type displayNameRequest struct {
+	UserID string `json:"user_id"`
+}
+
+func TestHandleJSON(t *testing.T) {
+	handler := rabbitmq.HandleJSON(func(ctx context.Context, req displayNameRequest) (string, error) {
+		if req.UserID == "" {
+			return "", errors.New("user_id required")
+		}
+		return "Ada " + req.UserID, nil
+	})
+
+	body, err := handler(context.Background(), amqp.Delivery{Body: []byte(`{"user_id":"42"}`)})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `"Ada 42"`, string(body))
+
+	_, err = handler(context.Background(), amqp.Delivery{Body: []byte(`{}`)})
+	assert.EqualError(t, err, "user_id required")
+
+	_, err = handler(context.Background(), amqp.Delivery{Body: []byte(`not json`)})
+	assert.ErrorContains(t, err, "invalid request")
+}
+
+func TestCallJSON_NilClient(t *testing.T) {
+	_, err := rabbitmq.CallJSON[displayNameRequest, string](context.Background(), nil, "users.display_name", displayNameRequest{UserID: "42"})
+
+	assert.ErrorIs(t, err, rabbitmq.ErrNotConnected)
+}
+
+func TestCall_ReplyMatchedByCorrelationID(t *testing.T) {
+	server := newFakeAMQP(t)
+	server.rpc = map[string]func(fakePublish) ([]byte, string){
+		"users.display_name": func(p fakePublish) ([]byte, string) { return []byte("Ada " + string(p.Body)), "" },
+	}
+	client := server.client(t, rabbitmq.Config{})
+	headers := amqp.Table{"trace": "abc"}
+
+	reply, err := client.Call(context.Background(), "users.display_name", headers, []byte("42"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada 42", string(reply.Body))
+	assert.Equal(t, amqp.Table{"trace": "abc"}, headers, "the caller's headers are not modified")
+	request := <-server.received
+	assert.Equal(t, request.CorrelationId, reply.CorrelationId)
+	assert.Equal(t, "amq.rabbitmq.reply-to", request.ReplyTo)
+}
+
+func TestCall_ConcurrentCallsGetTheirOwnReply(t *testing.T) {
+	server := newFakeAMQP(t)
+	server.rpc = map[string]func(fakePublish) ([]byte, string){
+		"echo": func(p fakePublish) ([]byte, string) { return p.Body, "" },
+	}
+	client := server.client(t, rabbitmq.Config{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(body string) {
+			defer wg.Done()
+			reply, err := client.Call(context.Background(), "echo", nil, []byte(body))
+			assert.NoError(t, err)
+			assert.Equal(t, body, string(reply.Body))
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+}
+
+func TestCall_Failures(t *testing.T) {
+	server := newFakeAMQP(t)
+	server.rpc = map[string]func(fakePublish) ([]byte, string){
+		"users.display_name": func(p fakePublish) ([]byte, string) { return nil, "user not found" },
+		"users.slow":         func(p fakePublish) ([]byte, string) { return nil, "" },
+	}
+	client := server.client(t, rabbitmq.Config{})
+
+	_, err := client.Call(context.Background(), "users.display_name", nil, nil)
+	var remote *rabbitmq.RemoteError
+	assert.ErrorAs(t, err, &remote)
+	assert.Equal(t, "user not found", remote.Message)
+
+	_, err = client.Call(context.Background(), "users.missing", nil, nil)
+	assert.ErrorIs(t, err, rabbitmq.ErrNoResponder)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.Call(ctx, "users.slow", nil, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCall_FailsWhenConnectionCloses(t *testing.T) {
+	server := newFakeAMQP(t)
+	server.rpc = map[string]func(fakePublish) ([]byte, string){
+		"users.slow": func(p fakePublish) ([]byte, string) { return nil, "" },
+	}
+	client := server.client(t, rabbitmq.Config{})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Call(context.Background(), "users.slow", nil, nil)
+		done <- err
+	}()
+	<-server.received
+	server.disconnect()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, rabbitmq.ErrNotConnected)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Call did not fail when the connection closed")
+	}
+}
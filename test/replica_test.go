@@ -0,0 +1,39 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/database"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseConfigFromEnv_Replicas(t *testing.T) {
+	t.Setenv("DATABASE_REPLICAS", "replica-1, replica-2:6432")
+
+	cfg, err := database.ConfigFromEnv()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"replica-1", "replica-2:6432"}, cfg.Replicas)
+}
+
+func TestReader_RoutesToHealthyReplica(t *testing.T) {
+	defer database.CloseReplicas()
+	cfg := database.Config{Host: "127.0.0.1", Port: "1", User: "app", Database: "users"}
+	poolConfig, err := cfg.PoolConfig()
+	assert.NoError(t, err)
+	// pgxpool connects lazily, so the pool can be created without a server.
+	replica, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	assert.NoError(t, err)
+
+	database.AddReplica("127.0.0.1:1", replica)
+
+	assert.Same(t, replica, database.Reader(context.Background()))
+	assert.Equal(t, database.DB, database.Reader(database.ForcePrimary(context.Background())))
+
+	database.CheckReplicas(context.Background())
+
+	assert.Equal(t, []database.ReplicaStatus{{Host: "127.0.0.1:1", Healthy: false}}, database.Replicas())
+	assert.Equal(t, database.DB, database.Reader(context.Background()))
+}
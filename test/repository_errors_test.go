@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapError_PgCodes(t *testing.T) {
+	cases := []struct {
+		code   string
+		status int
+	}{
+		{"23505", 409},
+		{"23503", 409},
+		{"23502", 400},
+		{"23514", 400},
+		{"22P02", 400},
+		{"40001", 409},
+		{"40P01", 409},
+		{"55P03", 503},
+		{"57014", 504},
+		{"XX000", 500},
+	}
+	for _, c := range cases {
+		err := fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: c.code, Message: "boom"})
+
+		problem := repository.MapError(context.Background(), err, "Insert Failed", "repository.Insert")
+
+		assert.Equal(t, c.status, problem.Status, c.code)
+		assert.Equal(t, "repository.Insert", problem.Instance)
+	}
+}
+
+func TestMapError_IncludesConstraintAndColumn(t *testing.T) {
+	err := &pgconn.PgError{Code: "23502", ColumnName: "email", ConstraintName: "users_email_nn", Detail: "Failing row contains (1, null)."}
+
+	problem := repository.MapError(context.Background(), err, "Insert Failed", "repository.Insert")
+
+	assert.Contains(t, problem.Detail, "constraint users_email_nn")
+	assert.Contains(t, problem.Detail, "column email")
+	assert.Contains(t, problem.Detail, "Failing row contains")
+}
+
+func TestMapError_ContextAndNoRows(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, models.StatusClientClosedRequest, repository.MapError(ctx, context.Canceled, "x", "i").Status)
+	assert.Equal(t, 504, repository.MapError(context.Background(), context.DeadlineExceeded, "x", "i").Status)
+	assert.Equal(t, 404, repository.MapError(context.Background(), pgx.ErrNoRows, "x", "i").Status)
+	assert.Equal(t, 500, repository.MapError(context.Background(), errors.New("unexpected"), "x", "i").Status)
+}
+
+func TestMapError_CancelledPgxQuery(t *testing.T) {
+	conn, err := pgconn.Connect(context.Background(), "postgres://test@"+fakePostgres(t)+"/test?sslmode=disable")
+	assert.NoError(t, err)
+	defer conn.Close(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// pgx wraps the cancellation of a query in its timeout error.
+	_, err = conn.Exec(ctx, "SELECT 1").ReadAll()
+	assert.True(t, pgconn.Timeout(err))
+
+	assert.Equal(t, models.StatusClientClosedRequest, repository.MapError(ctx, err, "x", "i").Status)
+	assert.Equal(t, models.StatusClientClosedRequest, repository.MapError(ctx, fmt.Errorf("query: %w", context.Canceled), "x", "i").Status)
+}
+
+func TestGetRouter_CancelledRequestIs499(t *testing.T) {
+	conn, err := pgconn.Connect(context.Background(), "postgres://test@"+fakePostgres(t)+"/test?sslmode=disable")
+	assert.NoError(t, err)
+	defer conn.Close(context.Background())
+	r := utils.GetRouter()
+	r.GET("/courses", func(c *gin.Context) {
+		// The fake never answers, so the query only ends when c is cancelled.
+		_, err := conn.Exec(c, "SELECT 1").ReadAll()
+		problem := repository.MapError(c, err, "Query Failed", c.Request.URL.Path)
+		c.JSON(problem.Status, problem)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/courses", nil).WithContext(ctx))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		assert.Equal(t, models.StatusClientClosedRequest, w.Code)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the query was not cancelled with the request")
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPut, "/courses/1", nil).WithContext(ctx)
+	c.Set(repository.ActorKey, "42")
+	c.Set(repository.ExpectedVersionKey, int64(3))
+
+	reqCtx := utils.RequestContext(c)
+	cancel()
+
+	version, ok := repository.ExpectedVersion(reqCtx)
+	assert.Equal(t, "42", repository.ActorFromContext(reqCtx))
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), version)
+	assert.ErrorIs(t, reqCtx.Err(), context.Canceled)
+}
+
+// fakePostgres accepts one connection, completes the startup handshake and
+// then ignores every query. It returns the address to connect to.
+func fakePostgres(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		backend := pgproto3.NewBackend(conn, conn)
+		if _, err := backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+		backend.Send(&pgproto3.AuthenticationOk{})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		if backend.Flush() != nil {
+			return
+		}
+		for {
+			if _, err := backend.Receive(); err != nil {
+				return
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRegisterErrorMapper_Overrides(t *testing.T) {
+	unregister := repository.RegisterErrorMapper(func(err error, instance string) (models.ProblemDetails, bool) {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "users_email_key" {
+			return models.NewProblemDetails(409, "Conflict", "Email already registered", instance), true
+		}
+		return models.ProblemDetails{}, false
+	})
+	t.Cleanup(unregister)
+
+	custom := repository.MapError(context.Background(), &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}, "x", "i")
+	other := repository.MapError(context.Background(), &pgconn.PgError{Code: "23505", ConstraintName: "courses_pkey"}, "x", "i")
+
+	assert.Equal(t, "Email already registered", custom.Detail)
+	assert.Contains(t, other.Detail, "courses_pkey")
+}
+
+func TestRegisterErrorMapper_Unregister(t *testing.T) {
+	unregister := repository.RegisterErrorMapper(func(err error, instance string) (models.ProblemDetails, bool) {
+		return models.NewProblemDetails(418, "Teapot", "mapped", instance), true
+	})
+	t.Cleanup(unregister)
+	err := &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}
+
+	mapped := repository.MapError(context.Background(), err, "x", "i")
+	unregister()
+	restored := repository.MapError(context.Background(), err, "x", "i")
+
+	assert.Equal(t, 418, mapped.GetStatus())
+	assert.Equal(t, 409, restored.GetStatus())
+}
@@ -0,0 +1,16 @@
+package test
+
+import (
+	"os"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/logger"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/rabbitmq"
+)
+
+// init points the logger at a FakeBroker, so the code under test can log
+// without a running RabbitMQ.
+func init() {
+	broker := rabbitmq.NewFakeBroker()
+	client := broker.NewClient("test", rabbitmq.Config{}, []string{logger.LogExchangeName, logger.StatsExchangeName})
+	logger.InitLoggerWithClient("test", logger.Error, os.Stdout, client)
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+type auditFields struct {
+	CreatedAt string `db:"created_at,readonly"`
+}
+
+type CourseTask struct {
+	ID       string `db:"id,pk,readonly"`
+	CourseID string
+	Title    string `db:"title"`
+	Secret   string `db:"-"`
+	auditFields
+}
+
+type renamedEntity struct {
+	Key  int `db:"key,pk"`
+	Name string
+}
+
+func (renamedEntity) TableName() string { return "entities" }
+
+func TestStructParser_TableName(t *testing.T) {
+	assert.Equal(t, `"course_tasks"`, repository.NewStructParser[CourseTask]().Table())
+	assert.Equal(t, `"entities"`, repository.NewStructParser[renamedEntity]().Table())
+}
+
+func TestStructParser_InsertQuery(t *testing.T) {
+	parser := repository.NewStructParser[CourseTask]()
+
+	query, args := parser.InsertQuery(CourseTask{ID: "1", CourseID: "c1", Title: "TP1", auditFields: auditFields{CreatedAt: "now"}})
+
+	assert.Equal(t, `INSERT INTO "course_tasks" ("course_id", "title") VALUES ($1, $2)`, query)
+	assert.Equal(t, []any{"c1", "TP1"}, args)
+}
+
+func TestStructParser_UpdateQuery(t *testing.T) {
+	parser := repository.NewStructParser[CourseTask]()
+
+	query, args := parser.UpdateQuery(&CourseTask{ID: "1", CourseID: "c1", Title: "TP1"})
+
+	assert.Equal(t, `UPDATE "course_tasks" SET "course_id" = $1, "title" = $2 WHERE "id" = $3`, query)
+	assert.Equal(t, []any{"c1", "TP1", "1"}, args)
+}
+
+func TestStructParser_GetQueryMany(t *testing.T) {
+	parser := repository.NewStructParser[CourseTask]()
+
+	query, args := parser.GetQueryMany(map[string]any{"title": "TP1", "course_id": []string{"c1", "c2"}, "id": nil})
+
+	assert.Equal(t, `SELECT "id", "course_id", "title", "created_at" FROM "course_tasks" WHERE "course_id" = ANY($1) AND "id" IS NULL AND "title" = $2`, query)
+	assert.Equal(t, []any{[]string{"c1", "c2"}, "TP1"}, args)
+}
+
+func TestStructParser_DeleteWithoutFiltersDeletesNothing(t *testing.T) {
+	parser := repository.NewStructParser[CourseTask]()
+
+	query, args := parser.DeleteQueryMany(nil)
+
+	assert.Equal(t, `DELETE FROM "course_tasks" WHERE FALSE`, query)
+	assert.Empty(t, args)
+}
+
+func TestStructParser_PageQueryAndCursor(t *testing.T) {
+	parser := repository.NewStructParser[CourseTask]()
+	page := repository.PageRequest{Limit: 2, OrderBy: []repository.OrderField{{Field: "title"}}}
+
+	query, args := parser.GetQueryPage(map[string]any{"course_id": "c1"}, page)
+	assert.Equal(t, `SELECT "id", "course_id", "title", "created_at" FROM "course_tasks" WHERE "course_id" = $1 ORDER BY "title" ASC, "id" ASC LIMIT $2`, query)
+	assert.Equal(t, []any{"c1", 2}, args)
+
+	cursor, err := parser.NextCursor(repository.Records[CourseTask]{{ID: "1", Title: "A"}, {ID: "2", Title: "B"}}, page)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cursor)
+
+	page.Cursor = cursor
+	query, args = parser.GetQueryPage(map[string]any{"course_id": "c1"}, page)
+	assert.Equal(t, `SELECT "id", "course_id", "title", "created_at" FROM "course_tasks" WHERE "course_id" = $1 AND (("title" > $2) OR ("title" = $2 AND "id" > $3)) ORDER BY "title" ASC, "id" ASC LIMIT $4`, query)
+	assert.Equal(t, []any{"c1", "B", "2", 2}, args)
+}
+
+type ForumPost struct {
+	ID        string  `db:"id,pk"`
+	Body      string  `db:"body"`
+	CreatedAt string  `db:"created_at"`
+	UpdatedAt string  `db:"updated_at"`
+	UpdatedBy string  `db:"updated_by"`
+	DeletedAt *string `db:"deleted_at"`
+}
+
+func TestStructParser_AuditColumns(t *testing.T) {
+	parser := repository.NewStructParser[ForumPost]()
+
+	query, args := parser.InsertQueryBy(ForumPost{ID: "p1", Body: "hi"}, "teacher-1")
+	assert.Equal(t, `INSERT INTO "forum_posts" ("id", "body", "created_at", "updated_at", "updated_by") VALUES ($1, $2, now(), now(), $3)`, query)
+	assert.Equal(t, []any{"p1", "hi", "teacher-1"}, args)
+
+	query, args = parser.UpdateQueryBy(ForumPost{ID: "p1", Body: "edited"}, "teacher-2")
+	assert.Equal(t, `UPDATE "forum_posts" SET "body" = $1, "updated_at" = now(), "updated_by" = $2 WHERE "id" = $3 AND "deleted_at" IS NULL`, query)
+	assert.Equal(t, []any{"edited", "teacher-2", "p1"}, args)
+}
+
+func TestStructParser_SoftDelete(t *testing.T) {
+	parser := repository.NewStructParser[ForumPost]()
+	assert.True(t, parser.SoftDeletes())
+	assert.False(t, repository.NewStructParser[CourseTask]().SoftDeletes())
+
+	query, args := parser.SoftDeleteQueryMany(map[string]any{"id": "p1"}, "admin")
+	assert.Equal(t, `UPDATE "forum_posts" SET "deleted_at" = now(), "updated_at" = now(), "updated_by" = $1 WHERE "id" = $2 AND "deleted_at" IS NULL`, query)
+	assert.Equal(t, []any{"admin", "p1"}, args)
+
+	query, _ = parser.RestoreQueryMany(map[string]any{"id": "p1"}, "")
+	assert.Equal(t, `UPDATE "forum_posts" SET "deleted_at" = NULL, "updated_at" = now() WHERE "id" = $1 AND "deleted_at" IS NOT NULL`, query)
+
+	query, _ = parser.GetAllQuery()
+	assert.Equal(t, `SELECT "id", "body", "created_at", "updated_at", "updated_by", "deleted_at" FROM "forum_posts" WHERE "deleted_at" IS NULL`, query)
+
+	query, _ = parser.WithDeleted().GetAllQuery()
+	assert.Equal(t, `SELECT "id", "body", "created_at", "updated_at", "updated_by", "deleted_at" FROM "forum_posts"`, query)
+}
+
+type enrollment struct {
+	CourseID string `db:"course_id,pk"`
+	UserID   string `db:"user_id,pk"`
+}
+
+func TestStructParser_UpdateQueryWithoutColumns(t *testing.T) {
+	tx := &fakeTx{}
+	repo := repository.NewRepository(repository.NewStructParser[enrollment]())
+	enrollments := repo.InTx(repository.NewTx(tx))
+
+	query, args := repository.NewStructParser[enrollment]().UpdateQuery(enrollment{CourseID: "c1", UserID: "u1"})
+	resp := enrollments.UpdateCtx(context.Background(), enrollment{CourseID: "c1", UserID: "u1"})
+
+	assert.Empty(t, query)
+	assert.Nil(t, args)
+	assert.Equal(t, 500, resp.GetStatus())
+	assert.Empty(t, tx.execs)
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/database"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t,
+		`SELECT * FROM users WHERE id IN (...) AND name = ? AND age > $n LIMIT ?`,
+		database.Fingerprint("SELECT *\n  FROM users WHERE id IN ($1, $2, $3) AND name = 'o''brien' AND age > $4 LIMIT 10"))
+}
+
+func TestTracer_AggregatesByFingerprint(t *testing.T) {
+	tracer := database.NewTracer()
+	run := func(sql string, tag string, err error) {
+		ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql, Args: []any{"secret"}})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag(tag), Err: err})
+	}
+
+	run(`UPDATE "grades" SET "score" = $1 WHERE "id" = $2`, "UPDATE 1", nil)
+	run(`UPDATE "grades" SET "score" = $1 WHERE "id" = $2`, "UPDATE 0", errors.New("boom"))
+	run(`DELETE FROM "grades" WHERE "id" = $1`, "DELETE 3", nil)
+
+	stats := map[string]database.QueryStats{}
+	for _, s := range tracer.Stats() {
+		stats[s.Fingerprint] = s
+	}
+	update := stats[`UPDATE "grades" SET "score" = $n WHERE "id" = $n`]
+	assert.Equal(t, int64(2), update.Calls)
+	assert.Equal(t, int64(1), update.Errors)
+	assert.Equal(t, int64(1), update.RowsAffected)
+	assert.Equal(t, int64(3), stats[`DELETE FROM "grades" WHERE "id" = $n`].RowsAffected)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	tracer.MetricsHandler()(c)
+	assert.Contains(t, w.Body.String(), `db_query_calls_total{query="DELETE FROM \"grades\" WHERE \"id\" = $n"} 1`)
+	assert.NotContains(t, w.Body.String(), "secret")
+}
+
+func TestTracer_MetricsGroupsSamplesByFamily(t *testing.T) {
+	tracer := database.NewTracer()
+	for _, sql := range []string{`SELECT 1`, `DELETE FROM "grades"`} {
+		ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("DELETE 1")})
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	tracer.MetricsHandler()(c)
+
+	var families []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		name := strings.Fields(line)[0]
+		if name == "#" {
+			name = strings.Fields(line)[2]
+		} else {
+			name = name[:strings.Index(name, "{")]
+		}
+		if len(families) == 0 || families[len(families)-1] != name {
+			assert.False(t, seen[name], "samples of %s are not contiguous", name)
+			seen[name] = true
+			families = append(families, name)
+		}
+	}
+	assert.Equal(t, []string{
+		"db_query_calls_total",
+		"db_query_errors_total",
+		"db_query_rows_affected_total",
+		"db_query_duration_seconds_total",
+		"db_query_duration_seconds_max",
+	}, families)
+}
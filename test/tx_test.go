@@ -0,0 +1,81 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTx_SavepointCommits(t *testing.T) {
+	outer := &fakeTx{}
+	posts := repository.NewRepository(repository.NewStructParser[ForumPost]())
+
+	resp := repository.NewTx(outer).WithTx(context.Background(), func(tx repository.Tx) error {
+		bound := posts.InTx(tx)
+		return repository.Check(bound.InsertCtx(context.Background(), ForumPost{ID: "p1", Body: "hi"}))
+	})
+
+	assert.Equal(t, 200, resp.GetStatus())
+	assert.Len(t, outer.children, 1)
+	assert.True(t, outer.children[0].committed)
+	assert.Len(t, outer.children[0].execs, 1)
+	assert.Empty(t, outer.execs)
+}
+
+func TestTx_RollsBackOnError(t *testing.T) {
+	outer := &fakeTx{}
+	conflict := models.NewProblemDetails(409, "Conflict", "Post already exists", "repository.Insert")
+
+	resp := repository.NewTx(outer).WithTx(context.Background(), func(tx repository.Tx) error {
+		return repository.ResponseError{Response: conflict}
+	})
+	failed := repository.NewTx(outer).WithTx(context.Background(), func(tx repository.Tx) error {
+		return errors.New("boom")
+	})
+
+	assert.Equal(t, conflict, resp)
+	assert.Equal(t, 500, failed.GetStatus())
+	assert.True(t, outer.children[0].rolledBack)
+	assert.True(t, outer.children[1].rolledBack)
+	assert.False(t, outer.rolledBack, "only the savepoint is rolled back")
+}
+
+func TestTx_RollsBackOnPanic(t *testing.T) {
+	outer := &fakeTx{}
+
+	assert.PanicsWithValue(t, "boom", func() {
+		repository.NewTx(outer).WithTx(context.Background(), func(tx repository.Tx) error {
+			panic("boom")
+		})
+	})
+
+	assert.True(t, outer.children[0].rolledBack)
+	assert.False(t, outer.children[0].committed)
+}
+
+func TestTx_NestedSavepointRollbackKeepsOuterWork(t *testing.T) {
+	outer := &fakeTx{}
+	posts := repository.NewRepository(repository.NewStructParser[ForumPost]())
+
+	resp := repository.NewTx(outer).WithTx(context.Background(), func(tx repository.Tx) error {
+		bound := posts.InTx(tx)
+		if err := repository.Check(bound.InsertCtx(context.Background(), ForumPost{ID: "p1"})); err != nil {
+			return err
+		}
+		// The failed savepoint is discarded; the outer insert is kept.
+		tx.WithTx(context.Background(), func(inner repository.Tx) error {
+			return errors.New("duplicate comment")
+		})
+		return nil
+	})
+
+	savepoint := outer.children[0]
+	assert.Equal(t, 200, resp.GetStatus())
+	assert.True(t, savepoint.committed)
+	assert.True(t, savepoint.children[0].rolledBack)
+	assert.Len(t, savepoint.execs, 1)
+}
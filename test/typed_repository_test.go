@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/models"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func forumPostRow(id, body string) []any {
+	return []any{id, body, "2024-01-01", "2024-01-02", "u1", (*string)(nil)}
+}
+
+func TestTypedRepository_FindDecodesRows(t *testing.T) {
+	tx := &fakeTx{rows: [][]any{forumPostRow("p1", "hi"), forumPostRow("p2", "bye")}}
+	repo := repository.NewStructRepository[ForumPost]()
+	posts := repo.InTx(repository.NewTx(tx))
+
+	found, problem := posts.Find(context.Background(), map[string]any{"updated_by": "u1"})
+	all, allProblem := posts.FindAll(context.Background())
+
+	assert.Nil(t, problem)
+	assert.Nil(t, allProblem)
+	assert.Equal(t, []ForumPost{
+		{ID: "p1", Body: "hi", CreatedAt: "2024-01-01", UpdatedAt: "2024-01-02", UpdatedBy: "u1"},
+		{ID: "p2", Body: "bye", CreatedAt: "2024-01-01", UpdatedAt: "2024-01-02", UpdatedBy: "u1"},
+	}, found)
+	assert.Equal(t, found, all)
+	assert.Contains(t, tx.queries[0], "WHERE")
+}
+
+func TestTypedRepository_FindOne(t *testing.T) {
+	tx := &fakeTx{rows: [][]any{forumPostRow("p1", "hi"), forumPostRow("p2", "bye")}}
+	repo := repository.NewStructRepository[ForumPost]()
+	posts := repo.InTx(repository.NewTx(tx))
+
+	post, problem := posts.FindOne(context.Background(), map[string]any{"id": "p1"})
+
+	assert.Nil(t, problem)
+	assert.Equal(t, "p1", post.ID)
+	assert.Equal(t, "hi", post.Body)
+}
+
+func TestTypedRepository_FindOneNotFound(t *testing.T) {
+	repo := repository.NewStructRepository[ForumPost]()
+	posts := repo.InTx(repository.NewTx(&fakeTx{}))
+
+	post, problem := posts.FindOne(context.Background(), map[string]any{"id": "missing"})
+
+	assert.Equal(t, ForumPost{}, post)
+	if assert.NotNil(t, problem) {
+		assert.Equal(t, 404, problem.GetStatus())
+		assert.Equal(t, "repository.FindOne", problem.Instance)
+	}
+}
+
+func TestTypedRepository_FindWhere(t *testing.T) {
+	tx := &fakeTx{rows: [][]any{forumPostRow("p1", "hi")}}
+	repo := repository.NewStructRepository[ForumPost]()
+	posts := repo.InTx(repository.NewTx(tx))
+
+	found, problem := posts.FindWhere(context.Background(), repository.Eq("body", "hi"))
+	_, invalid := posts.FindWhere(context.Background(), repository.Eq("password", "x"))
+
+	assert.Nil(t, problem)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "p1", found[0].ID)
+	if assert.NotNil(t, invalid) {
+		assert.Equal(t, 400, invalid.GetStatus())
+		assert.Equal(t, "repository.FindWhere", invalid.Instance)
+	}
+	assert.Len(t, tx.queries, 1, "an invalid filter is rejected before querying")
+}
+
+func TestTypedRepository_Respond(t *testing.T) {
+	tx := &fakeTx{rows: [][]any{forumPostRow("p1", "hi")}}
+	repo := repository.NewStructRepository[ForumPost]()
+	posts := repo.InTx(repository.NewTx(tx))
+	notFound := models.NewProblemDetails(404, "Not Found", "Resource not found", "repository.FindOne")
+
+	ok := posts.GetAllCtx(context.Background())
+	failed := repository.Respond(ForumPost{}, &notFound, "Fetched", "posts.Get")
+
+	assert.Equal(t, 200, ok.GetStatus())
+	assert.JSONEq(t, `[{"ID":"p1","Body":"hi","CreatedAt":"2024-01-01","UpdatedAt":"2024-01-02","UpdatedBy":"u1","DeletedAt":null}]`, ok.GetData())
+	assert.Equal(t, notFound, failed)
+}
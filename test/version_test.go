@@ -0,0 +1,101 @@
+package test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/middleware"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type Submission struct {
+	ID      string `db:"id,pk"`
+	Grade   int    `db:"grade"`
+	Version int64  `db:"version,version"`
+}
+
+func TestStructParser_VersionedUpdateQuery(t *testing.T) {
+	parser := repository.NewStructParser[Submission]()
+
+	query, args := parser.UpdateQuery(Submission{ID: "s1", Grade: 8, Version: 3})
+
+	assert.Equal(t, `UPDATE "submissions" SET "grade" = $1, "version" = "version" + 1 WHERE "version" = $2 AND "id" = $3`, query)
+	assert.Equal(t, []any{8, int64(3), "s1"}, args)
+}
+
+func TestStructParser_VersionedInsertStartsAtOne(t *testing.T) {
+	parser := repository.NewStructParser[Submission]()
+
+	query, args := parser.InsertQuery(Submission{ID: "s1", Grade: 8})
+
+	assert.Equal(t, `INSERT INTO "submissions" ("id", "grade", "version") VALUES ($1, $2, 1)`, query)
+	assert.Equal(t, []any{"s1", 8}, args)
+}
+
+func TestStructParser_WithVersion(t *testing.T) {
+	parser := repository.NewStructParser[Submission]()
+
+	updated := parser.WithVersion(&Submission{ID: "s1", Version: 1}, 7)
+	version, ok := parser.Version(updated)
+
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), version)
+	_, ok = repository.NewStructParser[CourseTask]().Version(CourseTask{})
+	assert.False(t, ok)
+}
+
+func TestParseETag(t *testing.T) {
+	for _, header := range []string{`"5"`, `W/"5"`, `5`} {
+		version, err := utils.ParseETag(header)
+		assert.NoError(t, err, header)
+		assert.Equal(t, int64(5), version, header)
+	}
+	_, err := utils.ParseETag(`"abc"`)
+	assert.Error(t, err)
+	assert.Equal(t, `"5"`, utils.FormatETag(5))
+}
+
+func TestIfMatch_SetsExpectedVersion(t *testing.T) {
+	r := gin.New()
+	var version int64
+	var found bool
+	r.Use(middleware.IfMatch())
+	r.PUT("/submissions/:id", func(c *gin.Context) {
+		version, found = repository.ExpectedVersion(c)
+	})
+
+	req := httptest.NewRequest("PUT", "/submissions/s1", nil)
+	req.Header.Set("If-Match", `"4"`)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.True(t, found)
+	assert.Equal(t, int64(4), version)
+
+	w := httptest.NewRecorder()
+	req = httptest.NewRequest("PUT", "/submissions/s1", nil)
+	req.Header.Set("If-Match", "nope")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestIfMatch_WildcardSkipsVersionCheck(t *testing.T) {
+	r := gin.New()
+	called, found := false, false
+	r.Use(middleware.IfMatch())
+	r.PUT("/submissions/:id", func(c *gin.Context) {
+		called = true
+		_, found = repository.ExpectedVersion(c)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/submissions/s1", nil)
+	req.Header.Set("If-Match", "*")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.True(t, called)
+	assert.False(t, found)
+}
@@ -60,6 +60,16 @@ func HandleError(c *gin.Context, statusCode int, errorMessage string, path strin
 			errorMessage,
 			path,
 		))
+	case http.StatusConflict:
+		c.IndentedJSON(statusCode, models.Conflict(
+			errorMessage,
+			path,
+		))
+	case http.StatusPreconditionFailed:
+		c.IndentedJSON(statusCode, models.PreconditionFailed(
+			errorMessage,
+			path,
+		))
 	default:
 		c.IndentedJSON(statusCode, models.InternalServerError(
 			errorMessage,
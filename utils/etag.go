@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FormatETag renders a row version as a strong ETag, e.g. 3 -> "3" (quoted).
+func FormatETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ParseETag extracts the row version from an ETag or If-Match header value.
+// Weak validators (W/"3") are accepted as well.
+func ParseETag(value string) (int64, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		unquoted = value
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ETag: %s", value)
+	}
+	return version, nil
+}
+
+// SetETag sets the ETag response header for a resource at the given version,
+// so clients can send it back in If-Match when updating.
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", FormatETag(version))
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/Class-Connect-GRUPO-5/microservices-common/database"
+	"github.com/Class-Connect-GRUPO-5/microservices-common/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestContext returns the context of the request handled by c carrying the
+// acting user (repository.ActorKey), the If-Match version
+// (repository.ExpectedVersionKey) and database.ForcePrimaryKey when they are
+// set. Pass it to Repository methods so queries are cancelled when the client
+// goes away (499) or the request deadline passes (504), also on engines that
+// were not built with GetRouter and so do not set ContextWithFallback.
+//
+// Example:
+//
+//	resp := tasks.GetByFilterCtx(utils.RequestContext(c), filter)
+func RequestContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if actor := c.GetString(repository.ActorKey); actor != "" {
+		ctx = repository.WithActor(ctx, actor)
+	}
+	if version, ok := c.Get(repository.ExpectedVersionKey); ok {
+		if version, ok := version.(int64); ok {
+			ctx = repository.WithExpectedVersion(ctx, version)
+		}
+	}
+	if c.GetBool(database.ForcePrimaryKey) {
+		ctx = database.ForcePrimary(ctx)
+	}
+	return ctx
+}
@@ -34,10 +34,15 @@ func ReadEnvironmentVariables() (string, string, string, string, string, string)
 }
 
 // getRouter initializes the Gin router with recovery middleware and debug logging.
+// ContextWithFallback is set so the gin context reports the cancellation and
+// deadline of the request, which lets handlers pass c itself to Repository
+// methods. The health probes are not mounted, so services that define their
+// own keep working; call health.RegisterRoutes(r) to mount them.
 // It returns a pointer to the router.
 func GetRouter() *gin.Engine {
 	logger.Logger.Debug("Initializing router")
 	r := gin.New()
+	r.ContextWithFallback = true
 	r.Use(gin.Recovery())
 	logger.Logger.Debug("Router initialized successfully")
 	return r